@@ -0,0 +1,116 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Transfer is the number of bytes sent to and received from GOBS.
+type Transfer struct {
+	Uploaded   int64
+	Downloaded int64
+}
+
+// TransferCounter sums the bytes of object requests per key prefix, e.g. per tenant for
+// chargeback. The prefix of a key is its first Depth path segments, "tenant-a/" for
+// "tenant-a/reports/2020.csv" with a Depth of 1; a Depth of 0 sums everything under "". The
+// bytes of object data and listings are counted as they go over the wire, including retries.
+// Keys are relative to the prefix of WithKeyPrefix. It's safe for concurrent use.
+type TransferCounter struct {
+	Depth int
+
+	mu      sync.Mutex
+	entries map[string]*Transfer
+}
+
+// Usage returns the bytes transferred per prefix so far.
+func (c *TransferCounter) Usage() map[string]Transfer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usage := make(map[string]Transfer, len(c.entries))
+	for prefix, transfer := range c.entries {
+		usage[prefix] = Transfer{
+			Uploaded:   atomic.LoadInt64(&transfer.Uploaded),
+			Downloaded: atomic.LoadInt64(&transfer.Downloaded),
+		}
+	}
+	return usage
+}
+
+// Reset sets all counts to zero, e.g. at the start of a billing period.
+func (c *TransferCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+func (c *TransferCounter) entry(key string) *Transfer {
+	prefix := ""
+	segments := strings.SplitAfter(key, "/")
+	if c.Depth > 0 && len(segments) > c.Depth {
+		prefix = strings.Join(segments[:c.Depth], "")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]*Transfer{}
+	}
+	entry, ok := c.entries[prefix]
+	if !ok {
+		entry = &Transfer{}
+		c.entries[prefix] = entry
+	}
+	return entry
+}
+
+// WithTransferCounter counts the bytes transferred by the service in counter. Without it
+// nothing is counted.
+func WithTransferCounter(counter *TransferCounter) Option {
+	return func(o *options) {
+		o.transferCounter = counter
+	}
+}
+
+// countingTransport counts the request and response bodies of requests for objects.
+type countingTransport struct {
+	base       http.RoundTripper
+	counter    *TransferCounter
+	bucketName string
+	stripKey   func(string) string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// path-style requests start with the bucket, virtual-hosted ones with the key
+	key := strings.TrimPrefix(req.URL.Path, "/")
+	if !strings.HasPrefix(req.URL.Host, t.bucketName+".") {
+		key = strings.TrimPrefix(key, t.bucketName)
+		key = strings.TrimPrefix(key, "/")
+	}
+	key = t.stripKey(key)
+	entry := t.counter.entry(key)
+	if req.Body != nil {
+		counted := *req
+		counted.Body = &countingReader{ReadCloser: req.Body, count: &entry.Uploaded}
+		req = &counted
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &countingReader{ReadCloser: resp.Body, count: &entry.Downloaded}
+	return resp, nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.count, int64(n))
+	return n, err
+}
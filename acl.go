@@ -0,0 +1,105 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const allUsersURI = "http://acs.amazonaws.com/groups/global/AllUsers"
+
+// ObjectACL is the access control list of an object.
+type ObjectACL struct {
+	OwnerID   string
+	OwnerName string
+	Grants    []Grant
+}
+
+// Grant gives a permission such as READ or FULL_CONTROL to a grantee, which is identified by
+// an ID for accounts and by a URI for groups like all users.
+type Grant struct {
+	GranteeID   string
+	GranteeName string
+	GranteeURI  string
+	Permission  string
+}
+
+// Public reports whether the object can be read by anyone.
+func (a *ObjectACL) Public() bool {
+	for _, grant := range a.Grants {
+		if grant.GranteeURI == allUsersURI && (grant.Permission == "READ" || grant.Permission == "FULL_CONTROL") {
+			return true
+		}
+	}
+	return false
+}
+
+type accessControlPolicy struct {
+	Owner struct {
+		ID          string `xml:"ID"`
+		DisplayName string `xml:"DisplayName"`
+	} `xml:"Owner"`
+	Grants []struct {
+		Grantee struct {
+			ID          string `xml:"ID"`
+			DisplayName string `xml:"DisplayName"`
+			URI         string `xml:"URI"`
+		} `xml:"Grantee"`
+		Permission string `xml:"Permission"`
+	} `xml:"AccessControlList>Grant"`
+}
+
+// GetObjectACL returns the grants of the object.
+func (s *service) GetObjectACL(path string) (*ObjectACL, error) {
+	query := url.Values{}
+	query.Set("acl", "")
+	policy := accessControlPolicy{}
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodGet, path, query, nil, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return xml.NewDecoder(resp.Body).Decode(&policy)
+	})
+	if err != nil {
+		return nil, err
+	}
+	acl := &ObjectACL{OwnerID: policy.Owner.ID, OwnerName: policy.Owner.DisplayName}
+	for _, grant := range policy.Grants {
+		acl.Grants = append(acl.Grants, Grant{
+			GranteeID:   grant.Grantee.ID,
+			GranteeName: grant.Grantee.DisplayName,
+			GranteeURI:  grant.Grantee.URI,
+			Permission:  grant.Permission,
+		})
+	}
+	return acl, nil
+}
+
+// SetObjectACL replaces the ACL of an existing object with one of the canned ACLs, e.g.
+// ACLPublicRead to make it public, without uploading it again.
+func (s *service) SetObjectACL(path, acl string) error {
+	switch acl {
+	case ACLPrivate, ACLPublicRead, ACLPublicReadWrite, ACLAuthenticatedRead, ACLBucketOwnerRead, ACLBucketOwnerFullControl:
+	default:
+		return fmt.Errorf("unknown canned ACL %s", acl)
+	}
+	query := url.Values{}
+	query.Set("acl", "")
+	header := http.Header{}
+	header.Set(aclHeader, acl)
+	start := time.Now()
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodPut, path, query, header, nil)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	s.emit(Event{Op: EventACL, Key: path, Err: err}, start)
+	return err
+}
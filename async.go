@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// UploadHandle represents an upload started by UploadFileAsync.
+type UploadHandle struct {
+	cancel context.CancelFunc
+	result chan error
+}
+
+// Cancel aborts the upload. Parts of an already started multipart upload are removed, unless
+// the service was created with WithKeepFailedUploads.
+func (h *UploadHandle) Cancel() {
+	h.cancel()
+}
+
+// Result delivers the final result of the upload exactly once.
+func (h *UploadHandle) Result() <-chan error {
+	return h.result
+}
+
+func (s *service) UploadFileAsync(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) *UploadHandle {
+	o := newUploadOptions(contentType, opts)
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := &UploadHandle{cancel: cancel, result: make(chan error, 1)}
+	size := int64(-1)
+	if objectSize != nil {
+		size = *objectSize
+	}
+	go func() {
+		defer cancel()
+		// the incomplete upload is aborted by putObject, which doesn't use ctx for it
+		err := s.uploadFile(ctx, path, data, size, o)
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		handle.result <- err
+		close(handle.result)
+	}()
+	return handle
+}
+
+// uploadWriter streams everything written to it into an upload running in the background.
+type uploadWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+	once sync.Once
+	err  error
+}
+
+func (w *uploadWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the upload and returns its error. Further calls return the same error.
+func (w *uploadWriter) Close() error {
+	w.once.Do(func() {
+		w.pw.Close()
+		w.err = <-w.done
+	})
+	return w.err
+}
+
+// UploadWriter returns a writer streaming into a new object at path, without buffering the data
+// beyond the part size or writing temp files. The writer must be closed to complete the upload;
+// the object only exists once Close returned, and Close reports whether the upload succeeded.
+// Writes fail with the upload's error as soon as it fails.
+func (s *service) UploadWriter(path, contentType string) (io.WriteCloser, error) {
+	if err := s3utils.CheckValidObjectName(path); err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	w := &uploadWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		_, err := s.putObject(context.Background(), path, pr, -1, minio.PutObjectOptions{ContentType: contentType})
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w, nil
+}
@@ -0,0 +1,58 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUploadFileAsyncCancel(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		fake := newFakeS3(t)
+		cancelCh := make(chan func(), 1)
+		var parts int32
+		// cancel the upload while its second part is sent
+		fake.fail = func(r *http.Request) int {
+			if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "" && atomic.AddInt32(&parts, 1) == 2 {
+				(<-cancelCh)()
+			}
+			return 0
+		}
+		opts := []Option{WithMultipartThreshold(minPartSize), WithPartSize(minPartSize)}
+		if keep {
+			opts = append(opts, WithKeepFailedUploads())
+		}
+		service := fake.newService(opts...)
+		data := make([]byte, 3*minPartSize)
+		size := int64(len(data))
+		handle := service.UploadFileAsync("large.bin", "", bytes.NewReader(data), &size)
+		cancelCh <- handle.Cancel
+		if err := <-handle.Result(); !errors.Is(err, context.Canceled) {
+			t.Errorf("cancelled upload returned %v", err)
+		}
+		want := 0
+		if keep {
+			want = 1
+		}
+		if n := fake.incompleteUploads(); n != want {
+			t.Errorf("with WithKeepFailedUploads %v %d incomplete uploads are left, want %d", keep, n, want)
+		}
+		fake.Close()
+	}
+}
+
+func TestUploadFileAsyncVerifySize(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	data := []byte("short")
+	size := int64(2 * len(data))
+	handle := service.UploadFileAsync("short.txt", "text/plain", bytes.NewReader(data), &size, WithVerifySize(true))
+	mismatch := &SizeMismatchError{}
+	if err := <-handle.Result(); !errors.As(err, &mismatch) {
+		t.Errorf("got %v, want a *SizeMismatchError", err)
+	}
+}
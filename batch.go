@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"context"
+	"sync"
+)
+
+// runBatch lists all objects under prefix and calls fn for each of them, at most concurrency
+// at a time. Failed keys are reported as *BatchError.
+func (s *service) runBatch(op, prefix string, concurrency int, fn func(obj ObjectInfo) error) error {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	return s.runObjects(op, s.listObjects(prefix, doneCh), concurrency, fn)
+}
+
+// runObjects calls fn for every object received from objects, at most concurrency at a time.
+// An object carrying an error stops the batch after the running calls are done.
+func (s *service) runObjects(op string, objects <-chan ObjectInfo, concurrency int, fn func(obj ObjectInfo) error) error {
+	return s.runObjectsContext(context.Background(), op, objects, concurrency, false, func(_ context.Context, obj ObjectInfo) error {
+		return fn(obj)
+	})
+}
+
+// runObjectsContext is runObjects passing a context to fn. With failFast the first failure
+// cancels the context and no further calls are started.
+func (s *service) runObjectsContext(ctx context.Context, op string, objects <-chan ObjectInfo, concurrency int, failFast bool, fn func(ctx context.Context, obj ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	wg := sync.WaitGroup{}
+	mu := sync.Mutex{}
+	sem := make(chan struct{}, concurrency)
+	errs := map[string]error{}
+	for obj := range objects {
+		if obj.Err != nil {
+			wg.Wait()
+			return s.listingError(op, errs, obj.Err)
+		}
+		if s.shuttingDown() {
+			mu.Lock()
+			errs[obj.Key] = ErrShuttingDown
+			mu.Unlock()
+			break
+		}
+		if failFast && ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(obj ObjectInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(ctx, obj); err != nil {
+				mu.Lock()
+				errs[obj.Key] = err
+				mu.Unlock()
+				if failFast {
+					cancel()
+				}
+			}
+		}(obj)
+	}
+	wg.Wait()
+	return newBatchError(op, errs)
+}
+
+// listingError returns the error of a batch operation whose listing failed with err after the
+// keys in errs failed, so Failures still reports them.
+func (s *service) listingError(op string, errs map[string]error, err error) error {
+	if len(errs) == 0 {
+		return s.wrapError(err)
+	}
+	return &BatchError{Op: op, Errors: errs, Err: s.wrapError(err)}
+}
@@ -0,0 +1,32 @@
+package s3
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunObjectsListingError(t *testing.T) {
+	errObject := errors.New("object failed")
+	errListing := errors.New("listing failed")
+	objects := make(chan ObjectInfo, 2)
+	objects <- ObjectInfo{Key: "a"}
+	objects <- ObjectInfo{Err: errListing}
+	close(objects)
+	s := &service{}
+	err := s.runObjects("remove", objects, 1, func(obj ObjectInfo) error {
+		return errObject
+	})
+	if got := Failures(err); len(got) != 1 || got["a"] != errObject {
+		t.Errorf("failures are %v, want a: %v", got, errObject)
+	}
+	if !errors.Is(err, errListing) {
+		t.Errorf("error is %v, want it to wrap %v", err, errListing)
+	}
+
+	objects = make(chan ObjectInfo, 1)
+	objects <- ObjectInfo{Err: errListing}
+	close(objects)
+	if err := s.runObjects("remove", objects, 1, func(obj ObjectInfo) error { return nil }); err != errListing {
+		t.Errorf("error is %v, want %v", err, errListing)
+	}
+}
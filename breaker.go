@@ -0,0 +1,166 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Defaults of CircuitBreakerPolicy.
+const (
+	defaultBreakerFailures = 5
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed passes calls through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails calls with ErrCircuitOpen until the cooldown is over.
+	CircuitOpen
+	// CircuitHalfOpen passes a single call through to probe whether the endpoint recovered.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerPolicy configures NewCircuitBreakerService. Failures is the number of
+// consecutive failed calls opening the circuit, 5 by default; Cooldown how long it stays open,
+// 30s by default. OnStateChange, if set, is called with every new state, e.g. to report the
+// outage to health checks. It must not call the service.
+type CircuitBreakerPolicy struct {
+	Failures      int
+	Cooldown      time.Duration
+	OnStateChange func(CircuitState)
+}
+
+// CircuitBreaker is the state shared by the calls of a NewCircuitBreakerService.
+type CircuitBreaker struct {
+	policy   CircuitBreakerPolicy
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// State returns the current state of the circuit.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow reports whether a call may go through, moving an open circuit to half-open once the
+// cooldown is over.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	changed := false
+	defer func() {
+		b.mu.Unlock()
+		if changed {
+			b.notify(CircuitHalfOpen)
+		}
+	}()
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.policy.Cooldown {
+			return false
+		}
+		b.state, changed = CircuitHalfOpen, true
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+	default:
+		return true
+	}
+	b.probing = true
+	return true
+}
+
+// record counts the outcome of a call. Only throttling, server and network errors are
+// failures, for batch operations those of any object; other errors show that the endpoint
+// answers. Canceled calls don't count.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	previous := b.state
+	b.probing = false
+	switch {
+	case errors.Is(err, context.Canceled):
+	case err != nil && isBreakerFailure(err):
+		b.failures++
+		if b.state == CircuitHalfOpen || b.failures >= b.policy.Failures {
+			b.state, b.openedAt = CircuitOpen, time.Now()
+		}
+	default:
+		b.failures = 0
+		b.state = CircuitClosed
+	}
+	state := b.state
+	b.mu.Unlock()
+	if state != previous {
+		b.notify(state)
+	}
+}
+
+func isBreakerFailure(err error) bool {
+	failures := Failures(err)
+	if failures == nil {
+		return isRetryable(err)
+	}
+	// a batch stopped by a server error, e.g. a failed listing
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) && batchErr.Err != nil && isRetryable(batchErr.Err) {
+		return true
+	}
+	for _, err := range failures {
+		if isRetryable(err) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *CircuitBreaker) notify(state CircuitState) {
+	if b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(state)
+	}
+}
+
+// NewCircuitBreakerService fails calls to inner with ErrCircuitOpen for a cooldown after a
+// number of consecutive calls failed with throttling, server or network errors, so a down
+// endpoint isn't hammered and callers notice the outage right away. After the cooldown a single
+// call probes the endpoint, closing the circuit if it succeeds. The returned CircuitBreaker
+// exposes the state. UploadFileAsync, IterateObjects and Raw aren't guarded: the pages of
+// an ObjectIterator are requested by Next, after IterateObjects returned.
+func NewCircuitBreakerService(inner Service, policy CircuitBreakerPolicy) (Service, *CircuitBreaker) {
+	if policy.Failures < 1 {
+		policy.Failures = defaultBreakerFailures
+	}
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = defaultBreakerCooldown
+	}
+	breaker := &CircuitBreaker{policy: policy}
+	return &decorator{inner: inner, call: func(ctx context.Context, method string, fn func() error) error {
+		if !breaker.allow() {
+			return ErrCircuitOpen
+		}
+		err := fn()
+		breaker.record(err)
+		return err
+	}}, breaker
+}
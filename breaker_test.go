@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+func TestCircuitBreakerRecord(t *testing.T) {
+	serverErr := minio.ErrorResponse{StatusCode: http.StatusServiceUnavailable, Code: "ServiceUnavailable"}
+	notFound := minio.ErrorResponse{StatusCode: http.StatusNotFound, Code: "NoSuchKey"}
+	tests := []struct {
+		name string
+		err  error
+		want CircuitState
+	}{
+		{"server error", serverErr, CircuitOpen},
+		{"not found", notFound, CircuitClosed},
+		{"batch with a server error", newBatchError("remove", map[string]error{"a": notFound, "b": serverErr}), CircuitOpen},
+		{"batch without server errors", newBatchError("remove", map[string]error{"a": notFound}), CircuitClosed},
+		{"batch stopped by a server error", &BatchError{Op: "remove", Errors: map[string]error{"a": notFound}, Err: serverErr}, CircuitOpen},
+		{"wrapped batch", fmt.Errorf("sync: %w", newBatchError("copy", map[string]error{"a": serverErr})), CircuitOpen},
+		{"other error", errors.New("invalid argument"), CircuitClosed},
+	}
+	for _, test := range tests {
+		breaker := &CircuitBreaker{policy: CircuitBreakerPolicy{Failures: 1, Cooldown: time.Minute}}
+		breaker.record(test.err)
+		if got := breaker.State(); got != test.want {
+			t.Errorf("%s: circuit is %s, want %s", test.name, got, test.want)
+		}
+	}
+}
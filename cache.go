@@ -0,0 +1,161 @@
+package s3
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// WithExistenceCache caches the results of FileExists per key for ttl. Uploads and removals
+// through the same service invalidate the affected keys, changes made by other clients may
+// go unnoticed until the entry expires. It is disabled by default.
+func WithExistenceCache(ttl time.Duration) Option {
+	return func(o *options) {
+		o.existenceCacheTTL = ttl
+	}
+}
+
+type existenceEntry struct {
+	exists  bool
+	expires time.Time
+}
+
+// existenceCache is safe for concurrent use; a nil cache caches nothing.
+type existenceCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]existenceEntry
+}
+
+func newExistenceCache(ttl time.Duration) *existenceCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &existenceCache{ttl: ttl, entries: map[string]existenceEntry{}}
+}
+
+func (c *existenceCache) get(key string) (exists, ok bool) {
+	if c == nil {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return false, false
+	}
+	return entry.exists, true
+}
+
+func (c *existenceCache) set(key string, exists bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = existenceEntry{exists: exists, expires: time.Now().Add(c.ttl)}
+}
+
+func (c *existenceCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// WithDownloadCache keeps the data returned by DownloadFileBytes in memory, up to maxBytes in
+// total, dropping the least recently used objects first. Cached objects are still requested,
+// but with If-None-Match, so GOBS only sends them again if their ETag changed. Uploads and
+// removals through the same service drop the affected objects. It is disabled by default.
+func WithDownloadCache(maxBytes int64) Option {
+	return func(o *options) {
+		o.downloadCacheSize = maxBytes
+	}
+}
+
+type bytesEntry struct {
+	key  string
+	etag string
+	data []byte
+}
+
+// bytesCache is an LRU cache of object data, safe for concurrent use; a nil cache caches nothing.
+type bytesCache struct {
+	maxBytes int64
+	mu       sync.Mutex
+	size     int64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+func newBytesCache(maxBytes int64) *bytesCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &bytesCache{maxBytes: maxBytes, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// get returns the cached data of key and its ETag. The data must not be modified.
+func (c *bytesCache) get(key string) (data []byte, etag string, ok bool) {
+	if c == nil {
+		return nil, "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, "", false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*bytesEntry)
+	return entry.data, entry.etag, true
+}
+
+func (c *bytesCache) set(key, etag string, data []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&bytesEntry{key: key, etag: etag, data: data})
+	c.size += int64(len(data))
+	for c.size > c.maxBytes {
+		c.remove(c.order.Back().Value.(*bytesEntry).key)
+	}
+}
+
+func (c *bytesCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+func (c *bytesCache) remove(key string) {
+	element, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(element)
+	delete(c.entries, key)
+	c.size -= int64(len(element.Value.(*bytesEntry).data))
+}
+
+// invalidate drops path from the caches after it was changed through the service.
+func (s *service) invalidate(path string) {
+	key := s.normalizeKey(path)
+	s.existsCache.invalidate(key)
+	s.bytesCache.invalidate(key)
+}
@@ -0,0 +1,153 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// The requests in this file replace minio-go calls that don't take a context, so retries,
+// deadlines and shutdown reach them.
+
+// removeObject deletes path, or the version versionID of it if it's not empty.
+func (s *service) removeObject(ctx context.Context, path, versionID string) error {
+	query := url.Values{}
+	if versionID != "" {
+		query.Set("versionId", versionID)
+	}
+	resp, err := s.doRaw(ctx, http.MethodDelete, path, query, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// listObjectsV2 requests a page of the listing of the keys under prefix, which is a key in the
+// bucket.
+func (s *service) listObjectsV2(ctx context.Context, prefix, token, delimiter string) (minio.ListBucketV2Result, error) {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if s.opts.listPageSize > 0 {
+		query.Set("max-keys", strconv.Itoa(s.opts.listPageSize))
+	}
+	result := minio.ListBucketV2Result{}
+	resp, err := s.send(ctx, s.bucketName, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
+// newMultipartUpload starts a multipart upload to key in bucket and returns its upload ID.
+func (s *service) newMultipartUpload(ctx context.Context, bucket, key string, header http.Header) (string, error) {
+	query := url.Values{}
+	query.Set("uploads", "")
+	resp, err := s.send(ctx, bucket, http.MethodPost, key, query, header, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	result := struct {
+		UploadID string `xml:"UploadId"`
+	}{}
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	return result.UploadID, err
+}
+
+// listObjectParts requests a page of the parts uploaded to a multipart upload of path.
+func (s *service) listObjectParts(ctx context.Context, path, uploadID string, marker int) (minio.ListObjectPartsResult, error) {
+	query := url.Values{}
+	query.Set("uploadId", uploadID)
+	query.Set("part-number-marker", strconv.Itoa(marker))
+	query.Set("max-parts", "1000")
+	result := minio.ListObjectPartsResult{}
+	resp, err := s.doRaw(ctx, http.MethodGet, path, query, nil, nil)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
+// getBucketLifecycle returns the lifecycle configuration of the bucket as XML, or "" if it has
+// none.
+func (s *service) getBucketLifecycle(ctx context.Context) (string, error) {
+	query := url.Values{}
+	query.Set("lifecycle", "")
+	resp, err := s.doRaw(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		if errorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return "", nil
+		}
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	return string(data), err
+}
+
+type objectLockConfiguration struct {
+	XMLName           xml.Name        `xml:"ObjectLockConfiguration"`
+	ObjectLockEnabled string          `xml:"ObjectLockEnabled"`
+	Rule              *objectLockRule `xml:"Rule,omitempty"`
+}
+
+type objectLockRule struct {
+	DefaultRetention struct {
+		Mode  RetentionMode `xml:"Mode"`
+		Days  int           `xml:"Days,omitempty"`
+		Years int           `xml:"Years,omitempty"`
+	} `xml:"DefaultRetention"`
+}
+
+// getObjectLock returns the object lock configuration of the bucket. Buckets without object
+// lock fail with the code ObjectLockConfigurationNotFoundError.
+func (s *service) getObjectLock(ctx context.Context) (*objectLockConfiguration, error) {
+	query := url.Values{}
+	query.Set("object-lock", "")
+	resp, err := s.doRaw(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	config := &objectLockConfiguration{}
+	if err := xml.NewDecoder(resp.Body).Decode(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// putObjectLock replaces the object lock configuration of the bucket.
+func (s *service) putObjectLock(ctx context.Context, config *objectLockConfiguration) error {
+	body, err := xml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	header := http.Header{}
+	header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	query := url.Values{}
+	query.Set("object-lock", "")
+	resp, err := s.doRaw(ctx, http.MethodPut, "", query, header, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
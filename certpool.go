@@ -0,0 +1,19 @@
+package s3
+
+import (
+	"crypto/x509"
+)
+
+// copyCertPool returns a copy of pool that can be extended, or of the system roots if pool is
+// nil.
+func copyCertPool(pool *x509.CertPool) (*x509.CertPool, error) {
+	if pool != nil {
+		return cloneCertPool(pool)
+	}
+	system, err := x509.SystemCertPool()
+	if err != nil {
+		// the system roots are unavailable on some platforms
+		return x509.NewCertPool(), nil
+	}
+	return system, nil
+}
@@ -0,0 +1,12 @@
+//go:build go1.19
+// +build go1.19
+
+package s3
+
+import (
+	"crypto/x509"
+)
+
+func cloneCertPool(pool *x509.CertPool) (*x509.CertPool, error) {
+	return pool.Clone(), nil
+}
@@ -0,0 +1,13 @@
+//go:build !go1.19
+// +build !go1.19
+
+package s3
+
+import (
+	"crypto/x509"
+	"errors"
+)
+
+func cloneCertPool(pool *x509.CertPool) (*x509.CertPool, error) {
+	return nil, errors.New("s3: WithRootCAs can't extend the RootCAs of WithTLSConfig before Go 1.19")
+}
@@ -0,0 +1,269 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// maxCopyObjectSize is the largest object S3 copies with a single CopyObject request.
+const maxCopyObjectSize = 5 * 1024 * 1024 * 1024
+
+// needsMultipartCopy reports whether an object of size bytes has to be copied part by part.
+func needsMultipartCopy(size int64) bool {
+	return size > maxCopyObjectSize
+}
+
+// CopyOption configures a single copy.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	tags   map[string]string
+	mapper func(key string) string
+}
+
+// WithCopyTags adds tags to the copy, e.g. to mark it as promoted. The copy keeps the tags of the
+// source; if a key exists in both, the value given here wins. The tags are set on the copy after
+// it was created.
+func WithCopyTags(tags map[string]string) CopyOption {
+	return func(o *copyOptions) {
+		o.tags = tags
+	}
+}
+
+// WithCopyKeyMapper transforms the destination path of the copy, e.g. to add an environment
+// prefix with the same mapper passed to WithKeyMapper for directory operations. If mapper
+// returns an empty string nothing is copied.
+func WithCopyKeyMapper(mapper func(key string) string) CopyOption {
+	return func(o *copyOptions) {
+		o.mapper = mapper
+	}
+}
+
+func (s *service) CopyFile(srcPath, dstPath string, opts ...CopyOption) error {
+	return s.CopyToBucket(srcPath, s.bucketName, dstPath, opts...)
+}
+
+func (s *service) CopyToBucket(srcPath, dstBucket, dstPath string, opts ...CopyOption) error {
+	o := copyOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.mapper != nil {
+		if dstPath = o.mapper(dstPath); dstPath == "" {
+			return nil
+		}
+	}
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(srcPath), minio.StatObjectOptions{})
+	if err != nil {
+		return s.wrapError(err)
+	}
+	if err := s.copyObject(context.Background(), info, dstBucket, dstPath); err != nil {
+		return err
+	}
+	if len(o.tags) == 0 {
+		return nil
+	}
+	return s.mergeCopyTags(srcPath, dstBucket, dstPath, o.tags)
+}
+
+// CopyResult reports what CopyChanged did.
+type CopyResult struct {
+	Copied  int
+	Skipped int
+	Failed  int
+}
+
+// CopyDirectory copies the objects below srcPrefix to the same keys below dstPrefix server-side.
+// The keys below dstPrefix can be changed with WithKeyMapper and WithFlatten. Objects that
+// couldn't be copied are reported in a *BatchError.
+func (s *service) CopyDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error {
+	_, err := s.copyPrefix(srcPrefix, dstPrefix, false, false, newDirectoryOptions(opts))
+	return err
+}
+
+// MoveDirectory moves the objects below srcPrefix to the same keys below dstPrefix, e.g. to
+// rename a folder without downloading it. The keys can be changed like for CopyDirectory. Every
+// object is copied server-side and deleted once its copy succeeded, so an object whose copy
+// failed is kept. Deleted objects don't go to the trash, see WithTrashPrefix. Objects that
+// couldn't be moved are reported in a *BatchError, they are left either only at the source or
+// at both keys.
+func (s *service) MoveDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error {
+	_, err := s.copyPrefix(srcPrefix, dstPrefix, false, true, newDirectoryOptions(opts))
+	return err
+}
+
+// CopyChanged is CopyDirectory skipping objects whose copy already has the same ETag, e.g. for
+// incremental backups. Copies of objects above 5 GiB get a new multipart ETag and are copied
+// on every run.
+func (s *service) CopyChanged(srcPrefix, dstPrefix string, opts ...DirectoryOption) (*CopyResult, error) {
+	return s.copyPrefix(srcPrefix, dstPrefix, true, false, newDirectoryOptions(opts))
+}
+
+// copyPrefix copies the objects below srcPrefix to dstPrefix, with skipUnchanged only those whose
+// ETag differs from the existing copy. With remove the sources are deleted after their copy.
+func (s *service) copyPrefix(srcPrefix, dstPrefix string, skipUnchanged, remove bool, o directoryOptions) (*CopyResult, error) {
+	if strings.HasPrefix(s.normalizeKey(dstPrefix), s.normalizeKey(srcPrefix)) {
+		// the listing would return the copies again
+		return nil, fmt.Errorf("s3 can't copy %q into itself (%q)", srcPrefix, dstPrefix)
+	}
+	result := &CopyResult{}
+	mu := sync.Mutex{}
+	used := map[string]bool{}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	objectCh := s.listObjects(srcPrefix, doneCh)
+	err := s.runObjectsContext(context.Background(), "copy", objectCh, o.concurrency, o.failFast, func(ctx context.Context, obj ObjectInfo) error {
+		if !o.matches(obj, s.normalizeKey(srcPrefix)) {
+			mu.Lock()
+			result.Skipped++
+			mu.Unlock()
+			return nil
+		}
+		mu.Lock()
+		name, err := o.localName(obj, s.normalizeKey(srcPrefix), used)
+		if err == nil && name == "" {
+			result.Skipped++
+		}
+		mu.Unlock()
+		if err != nil || name == "" {
+			return err
+		}
+		dstPath := name
+		if dstPrefix != "" {
+			dstPath = strings.TrimSuffix(dstPrefix, "/") + "/" + name
+		}
+		if skipUnchanged {
+			dst, err := s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(dstPath), minio.StatObjectOptions{})
+			if err != nil && !isNotFound(err) {
+				return s.wrapError(err)
+			}
+			if err == nil && dst.ETag == obj.ETag {
+				mu.Lock()
+				result.Skipped++
+				mu.Unlock()
+				return nil
+			}
+		}
+		srcPath := obj.Key
+		obj.Key = s.key(obj.Key)
+		if err := s.copyObject(ctx, obj, s.bucketName, dstPath); err != nil {
+			return err
+		}
+		if remove {
+			start := time.Now()
+			err := s.retry(ctx, func(ctx context.Context) error {
+				return s.removeObject(ctx, srcPath, "")
+			})
+			s.invalidate(srcPath)
+			s.emit(Event{Op: EventDelete, Key: srcPath, Err: err}, start)
+			if err != nil {
+				return err
+			}
+		}
+		mu.Lock()
+		result.Copied++
+		mu.Unlock()
+		return nil
+	})
+	result.Failed = len(Failures(err))
+	return result, err
+}
+
+// mergeCopyTags sets the tags of srcPath merged with tags on the copy at dstPath.
+func (s *service) mergeCopyTags(srcPath, dstBucket, dstPath string, tags map[string]string) error {
+	ctx := context.Background()
+	merged, err := s.getObjectTags(ctx, srcPath)
+	if err != nil {
+		return err
+	}
+	for key, value := range tags {
+		merged[key] = value
+	}
+	dstKey := dstPath
+	if dstBucket == s.bucketName {
+		dstKey = s.key(dstPath)
+	}
+	start := time.Now()
+	err = s.retry(ctx, func(ctx context.Context) error {
+		return s.s3Client.PutObjectTaggingWithContext(ctx, dstBucket, dstKey, merged)
+	})
+	s.emit(Event{Op: EventTag, Bucket: dstBucket, Key: dstPath, Err: err}, start)
+	return err
+}
+
+// copyObject copies the object described by src, as returned by StatObject, server-side, using
+// a multipart copy for objects above the single copy limit. The source's metadata is kept.
+func (s *service) copyObject(ctx context.Context, src ObjectInfo, dstBucket, dstPath string) error {
+	dstKey := dstPath
+	if dstBucket == s.bucketName {
+		dstKey = s.key(dstPath)
+	}
+	if dstBucket == s.bucketName {
+		defer s.invalidate(dstPath)
+	}
+	start := time.Now()
+	err := s.retry(ctx, func(ctx context.Context) error {
+		if needsMultipartCopy(src.Size) {
+			return s.copyMultipart(ctx, src.Key, dstBucket, dstKey)
+		}
+		_, err := minio.Core{Client: s.s3Client}.CopyObjectWithContext(ctx, s.bucketName, src.Key, dstBucket, dstKey, nil)
+		return err
+	})
+	s.emit(Event{Op: EventCopy, Bucket: dstBucket, Key: dstPath, Source: s.stripKey(src.Key), Size: src.Size, Err: err}, start)
+	return err
+}
+
+// minCopyPartSize is the smallest part of a multipart copy. Larger objects use larger parts to
+// stay within 10000 parts.
+const minCopyPartSize = 512 * 1024 * 1024
+
+// copyMultipart copies the object at srcKey with UploadPartCopy in ranges. The upload is created
+// with the metadata of the source, which UploadPartCopy doesn't copy.
+func (s *service) copyMultipart(ctx context.Context, srcKey, dstBucket, dstKey string) error {
+	info, err := s.s3Client.StatObjectWithContext(ctx, s.bucketName, srcKey, minio.StatObjectOptions{})
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	for key, value := range metadataHeaders(storedMetadata(info.Metadata)) {
+		header.Set(key, value)
+	}
+	id, err := s.newMultipartUpload(ctx, dstBucket, dstKey, header)
+	if err != nil {
+		return err
+	}
+	core := minio.Core{Client: s.s3Client}
+	partSize := (info.Size + maxPartNumber - 1) / maxPartNumber
+	if partSize < minCopyPartSize {
+		partSize = minCopyPartSize
+	}
+	parts := []minio.CompletePart{}
+	for offset, number := int64(0), 1; offset < info.Size; offset, number = offset+partSize, number+1 {
+		length := partSize
+		if offset+length > info.Size {
+			length = info.Size - offset
+		}
+		var part minio.CompletePart
+		part, err = core.CopyObjectPartWithContext(ctx, s.bucketName, srcKey, dstBucket, dstKey, id, number, offset, length, nil)
+		if err != nil {
+			break
+		}
+		parts = append(parts, part)
+	}
+	if err == nil {
+		_, err = core.CompleteMultipartUploadWithContext(ctx, dstBucket, dstKey, id, parts)
+	}
+	if err != nil {
+		// ctx may be the reason of the failure
+		if abortErr := core.AbortMultipartUploadWithContext(context.Background(), dstBucket, dstKey, id); abortErr != nil {
+			s.logger.Printf("s3: failed to abort multipart copy %s to %s: %v", id, dstKey, abortErr)
+		}
+	}
+	return err
+}
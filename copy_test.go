@@ -0,0 +1,186 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNeedsMultipartCopy(t *testing.T) {
+	tests := []struct {
+		size int64
+		want bool
+	}{
+		{0, false},
+		{maxCopyObjectSize - 1, false},
+		{maxCopyObjectSize, false},
+		{maxCopyObjectSize + 1, true},
+		{2 * maxCopyObjectSize, true},
+	}
+	for _, test := range tests {
+		if got := needsMultipartCopy(test.size); got != test.want {
+			t.Errorf("needsMultipartCopy(%d) = %v, want %v", test.size, got, test.want)
+		}
+	}
+}
+
+func TestCopyMultipart(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService().(*service)
+	data := bytes.Repeat([]byte("gobs"), 1000)
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+	header.Set("X-Amz-Meta-Owner", "team")
+	fake.put("src.txt", data, header)
+	if err := service.copyMultipart(context.Background(), "src.txt", fakeBucket, "dst.txt"); err != nil {
+		t.Fatal(err)
+	}
+	copied, ok := fake.object("dst.txt")
+	if !ok {
+		t.Fatal("dst.txt wasn't created")
+	}
+	if !bytes.Equal(copied.data, data) {
+		t.Fatalf("copied %d bytes, want %d", len(copied.data), len(data))
+	}
+	if got := copied.header.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type of the copy is %q, want text/plain", got)
+	}
+	if got := copied.header.Get("X-Amz-Meta-Owner"); got != "team" {
+		t.Errorf("X-Amz-Meta-Owner of the copy is %q, want team", got)
+	}
+	if n := fake.incompleteUploads(); n != 0 {
+		t.Errorf("%d multipart uploads left", n)
+	}
+}
+
+func TestCopyDirectoryKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []DirectoryOption
+		want []string
+	}{
+		{"same keys", nil, []string{"dst/a.txt", "dst/sub/a.txt", "dst/sub/b.log"}},
+		{"key mapper", []DirectoryOption{WithKeyMapper(func(key string) string {
+			if strings.HasSuffix(key, ".log") {
+				return ""
+			}
+			return "mapped/" + key
+		})}, []string{"dst/mapped/a.txt", "dst/mapped/sub/a.txt"}},
+		{"flatten", []DirectoryOption{WithFlatten(CollisionSuffix), WithConcurrency(1)}, []string{"dst/a.txt", "dst/a_1.txt", "dst/b.log"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFakeS3(t)
+			defer fake.Close()
+			service := fake.newService()
+			for _, key := range []string{"src/a.txt", "src/sub/a.txt", "src/sub/b.log"} {
+				fake.put(key, []byte(key), nil)
+			}
+			if err := service.CopyDirectory("src/", "dst/", test.opts...); err != nil {
+				t.Fatal(err)
+			}
+			objects, err := service.ListObjects("dst/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, obj := range objects {
+				keys = append(keys, obj.Key)
+			}
+			if strings.Join(keys, " ") != strings.Join(test.want, " ") {
+				t.Errorf("copied to %v, want %v", keys, test.want)
+			}
+		})
+	}
+}
+
+func TestCopyDirectoryFlattenCollision(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	fake.put("src/a.txt", []byte("a"), nil)
+	fake.put("src/sub/a.txt", []byte("sub"), nil)
+	err := service.CopyDirectory("src/", "dst/", WithFlatten(CollisionError))
+	if len(Failures(err)) != 1 {
+		t.Fatalf("got %v, want one colliding object", err)
+	}
+}
+
+func TestCopyFileKeyMapper(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	fake.put("src.txt", []byte("data"), nil)
+	mapper := func(key string) string {
+		if strings.HasPrefix(key, "skip/") {
+			return ""
+		}
+		return "prod/" + key
+	}
+	if err := service.CopyFile("src.txt", "dst.txt", WithCopyKeyMapper(mapper)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.object("prod/dst.txt"); !ok {
+		t.Error("prod/dst.txt wasn't created")
+	}
+	if err := service.CopyFile("src.txt", "skip/dst.txt", WithCopyKeyMapper(mapper)); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"dst.txt", "skip/dst.txt", "prod/skip/dst.txt"} {
+		if _, ok := fake.object(key); ok {
+			t.Errorf("%s was created", key)
+		}
+	}
+}
+
+func TestMoveDirectorySlashes(t *testing.T) {
+	for _, prefixes := range [][2]string{{"src/", "dst/"}, {"src/", "dst"}, {"src", "dst/"}, {"src", "dst"}} {
+		fake := newFakeS3(t)
+		service := fake.newService()
+		fake.put("src/a.txt", []byte("a"), nil)
+		if err := service.MoveDirectory(prefixes[0], prefixes[1]); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fake.object("dst/a.txt"); !ok {
+			t.Errorf("MoveDirectory(%q, %q) didn't move src/a.txt to dst/a.txt: %v", prefixes[0], prefixes[1], fake.sortedKeys())
+		}
+		fake.Close()
+	}
+}
+
+func TestCopyDirectoryFailFastCancels(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	started := make(chan struct{})
+	// the copy of src/a fails once the copy of src/b is running, which then waits for its
+	// cancellation
+	fake.fail = func(r *http.Request) int {
+		switch strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/") {
+		case fakeBucket + "/src/a":
+			<-started
+			return http.StatusForbidden
+		case fakeBucket + "/src/b":
+			close(started)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return 0
+	}
+	fake.put("src/a", []byte("a"), nil)
+	fake.put("src/b", []byte("b"), nil)
+	err := fake.newService().CopyDirectory("src/", "dst/", WithFailFast(), WithConcurrency(2))
+	failures := Failures(err)
+	if !errors.Is(failures["src/b"], context.Canceled) {
+		t.Errorf("copy of src/b returned %v, want it to be cancelled", failures["src/b"])
+	}
+	if _, ok := fake.object("dst/b"); ok {
+		t.Error("cancelled copy of src/b created dst/b")
+	}
+}
@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"sync"
+
+	"github.com/minio/minio-go/v6/pkg/credentials"
+)
+
+// rotatingCredentials is a credentials provider whose keys can be replaced while requests are
+// being signed with them, see Reconfigure.
+type rotatingCredentials struct {
+	mu    sync.Mutex
+	value credentials.Value
+}
+
+func newRotatingCredentials(accessKey, secret, token string) *rotatingCredentials {
+	r := &rotatingCredentials{}
+	r.set(accessKey, secret, token)
+	return r
+}
+
+func (r *rotatingCredentials) set(accessKey, secret, token string) credentials.Value {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.value
+	r.value = credentials.Value{
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secret,
+		SessionToken:    token,
+		SignerType:      credentials.SignatureV4,
+	}
+	return previous
+}
+
+func (r *rotatingCredentials) Retrieve() (credentials.Value, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.value.AccessKeyID == "" || r.value.SecretAccessKey == "" {
+		return credentials.Value{SignerType: credentials.SignatureAnonymous}, nil
+	}
+	return r.value, nil
+}
+
+// IsExpired is false, Reconfigure expires the cached credentials when it replaces them.
+func (r *rotatingCredentials) IsExpired() bool {
+	return false
+}
+
+// Reconfigure replaces the credentials of the service, e.g. after a key rotation, keeping the
+// connections and caches. Requests already sent are not affected, later ones are signed with
+// the new keys. With verify the bucket is accessed with the new keys and the old ones are
+// restored if that fails, the error is a *StartupError like from NewService.
+func (s *service) Reconfigure(accessKey, secret, token string, verify bool) error {
+	s.reconfigureMu.Lock()
+	defer s.reconfigureMu.Unlock()
+	previous := s.provider.set(accessKey, secret, token)
+	s.creds.Expire()
+	if !verify {
+		return nil
+	}
+	exists, err := s.s3Client.BucketExists(s.bucketName)
+	if err == nil && exists {
+		return nil
+	}
+	s.provider.set(previous.AccessKeyID, previous.SecretAccessKey, previous.SessionToken)
+	s.creds.Expire()
+	endpoint := s.s3Client.EndpointURL().Host
+	if err != nil {
+		return newStartupError(endpoint, s.bucketName, endpointRegion(endpoint), err)
+	}
+	return &StartupError{
+		Endpoint: endpoint,
+		Bucket:   s.bucketName,
+		Region:   endpointRegion(endpoint),
+		Kind:     ErrBucketNotFound,
+	}
+}
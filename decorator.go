@@ -0,0 +1,701 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// Metrics receives the outcome of every call made through NewMetricsService.
+type Metrics interface {
+	ObserveCall(method string, duration time.Duration, err error)
+}
+
+// RetryPolicy configures NewRetryingService. A nil Backoff uses exponential backoff with full
+// jitter starting at 100ms and capped at 10s.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     BackoffStrategy
+}
+
+// unreplayable lists the methods consuming a reader, writer or channel of the caller, or
+// calling back into it, which can't be repeated once they failed.
+var unreplayable = map[string]bool{
+	"UploadFile":             true,
+	"UploadFileWithOptions":  true,
+	"UploadFileWithResult":   true,
+	"UploadFileWithSHA256":   true,
+	"UploadBatch":            true,
+	"UploadPart":             true,
+	"UploadNDJSON":           true,
+	"UploadJSONFileWithLink": true,
+	"AppendToObject":         true,
+	"DownloadTo":             true,
+	"ForEachObject":          true,
+	"ExportManifest":         true,
+}
+
+// decorator forwards every method of Service to inner through call, which can log, measure,
+// retry or block it. ctx is the context passed to the method, or context.Background() for
+// methods without one. UploadFileAsync, IterateObjects and Raw don't fail and are forwarded
+// directly.
+type decorator struct {
+	inner Service
+	call  func(ctx context.Context, method string, fn func() error) error
+}
+
+// NewLoggingService logs the duration and error of every call to inner.
+func NewLoggingService(inner Service, logger Logger) Service {
+	return &decorator{inner: inner, call: func(ctx context.Context, method string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		if err != nil {
+			logger.Printf("s3: %s failed after %s: %v", method, time.Since(start), err)
+		} else {
+			logger.Printf("s3: %s took %s", method, time.Since(start))
+		}
+		return err
+	}}
+}
+
+// NewMetricsService reports every call to inner to metrics.
+func NewMetricsService(inner Service, metrics Metrics) Service {
+	return &decorator{inner: inner, call: func(ctx context.Context, method string, fn func() error) error {
+		start := time.Now()
+		err := fn()
+		metrics.ObserveCall(method, time.Since(start), err)
+		return err
+	}}
+}
+
+// NewRetryingService repeats calls to inner failing with throttling, server or network errors,
+// on top of any retries inner does itself (WithRetry). Methods reading from or writing to a
+// caller's reader, writer or channel aren't retried, the data is gone after the first attempt.
+// Methods taking a context stop waiting for the next attempt once it is done.
+func NewRetryingService(inner Service, policy RetryPolicy) Service {
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = defaultBackoff
+	}
+	return &decorator{inner: inner, call: func(ctx context.Context, method string, fn func() error) error {
+		for attempt := 0; ; attempt++ {
+			err := fn()
+			if err == nil || unreplayable[method] || attempt+1 >= policy.MaxAttempts || !isRetryable(err) {
+				return err
+			}
+			timer := time.NewTimer(backoff.NextDelay(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}}
+}
+
+func (d *decorator) AddLifeCycleRule(ruleId, folderPath string, daysToExpiry int) error {
+	return d.call(context.Background(), "AddLifeCycleRule", func() error {
+		return d.inner.AddLifeCycleRule(ruleId, folderPath, daysToExpiry)
+	})
+}
+
+func (d *decorator) AddLifeCycleRuleWithFilter(ruleId string, filter LifecycleFilter, daysToExpiry int) error {
+	return d.call(context.Background(), "AddLifeCycleRuleWithFilter", func() error {
+		return d.inner.AddLifeCycleRuleWithFilter(ruleId, filter, daysToExpiry)
+	})
+}
+
+func (d *decorator) AddAbortIncompleteUploadRule(ruleId, prefix string, daysAfterInitiation int) error {
+	return d.call(context.Background(), "AddAbortIncompleteUploadRule", func() error {
+		return d.inner.AddAbortIncompleteUploadRule(ruleId, prefix, daysAfterInitiation)
+	})
+}
+
+func (d *decorator) UploadFile(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) error {
+	return d.call(context.Background(), "UploadFile", func() error {
+		return d.inner.UploadFile(path, contentType, data, objectSize, opts...)
+	})
+}
+
+func (d *decorator) UploadFileWithOptions(path string, data io.Reader, objectSize *int64, opts minio.PutObjectOptions) error {
+	return d.call(context.Background(), "UploadFileWithOptions", func() error {
+		return d.inner.UploadFileWithOptions(path, data, objectSize, opts)
+	})
+}
+
+func (d *decorator) UploadFileWithResult(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) (*UploadResult, error) {
+	var result *UploadResult
+	err := d.call(context.Background(), "UploadFileWithResult", func() (err error) {
+		result, err = d.inner.UploadFileWithResult(path, contentType, data, objectSize, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UploadFileWithSHA256(path, contentType string, data io.Reader, size int64, sum string, opts ...UploadOption) error {
+	return d.call(context.Background(), "UploadFileWithSHA256", func() error {
+		return d.inner.UploadFileWithSHA256(path, contentType, data, size, sum, opts...)
+	})
+}
+
+func (d *decorator) UploadLocalFile(localPath, path, contentType string, opts ...UploadOption) error {
+	return d.call(context.Background(), "UploadLocalFile", func() error {
+		return d.inner.UploadLocalFile(localPath, path, contentType, opts...)
+	})
+}
+
+func (d *decorator) UploadBatch(ctx context.Context, items map[string]UploadItem) error {
+	return d.call(ctx, "UploadBatch", func() error {
+		return d.inner.UploadBatch(ctx, items)
+	})
+}
+
+func (d *decorator) InitiateUpload(path, contentType string) (string, error) {
+	var result string
+	err := d.call(context.Background(), "InitiateUpload", func() (err error) {
+		result, err = d.inner.InitiateUpload(path, contentType)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UploadPart(uploadID string, partNumber int, data io.Reader) error {
+	return d.call(context.Background(), "UploadPart", func() error {
+		return d.inner.UploadPart(uploadID, partNumber, data)
+	})
+}
+
+func (d *decorator) CompleteUpload(uploadID string) error {
+	return d.call(context.Background(), "CompleteUpload", func() error {
+		return d.inner.CompleteUpload(uploadID)
+	})
+}
+
+func (d *decorator) AbortUpload(uploadID string) error {
+	return d.call(context.Background(), "AbortUpload", func() error {
+		return d.inner.AbortUpload(uploadID)
+	})
+}
+
+func (d *decorator) UploadFileAsync(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) *UploadHandle {
+	return d.inner.UploadFileAsync(path, contentType, data, objectSize, opts...)
+}
+
+func (d *decorator) GetFileUrl(path string, expiration time.Duration, opts ...PresignOption) (*url.URL, error) {
+	var result *url.URL
+	err := d.call(context.Background(), "GetFileUrl", func() (err error) {
+		result, err = d.inner.GetFileUrl(path, expiration, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) GetFileUrlString(path string, expiration time.Duration, opts ...PresignOption) (string, error) {
+	var result string
+	err := d.call(context.Background(), "GetFileUrlString", func() (err error) {
+		result, err = d.inner.GetFileUrlString(path, expiration, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) GetFileUrls(ctx context.Context, paths []string, expiration time.Duration, opts ...PresignOption) (map[string]*url.URL, error) {
+	var result map[string]*url.URL
+	err := d.call(ctx, "GetFileUrls", func() (err error) {
+		result, err = d.inner.GetFileUrls(ctx, paths, expiration, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) PublicURL(path string) (*url.URL, error) {
+	var result *url.URL
+	err := d.call(context.Background(), "PublicURL", func() (err error) {
+		result, err = d.inner.PublicURL(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) VerifyURL(u *url.URL) error {
+	return d.call(context.Background(), "VerifyURL", func() error {
+		return d.inner.VerifyURL(u)
+	})
+}
+
+func (d *decorator) ClockSkew() (time.Duration, error) {
+	var result time.Duration
+	err := d.call(context.Background(), "ClockSkew", func() (err error) {
+		result, err = d.inner.ClockSkew()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) GenerateUploadForm(keyPrefix string, maxSize int64, allowedTypes []string, expiry time.Duration) (*UploadForm, error) {
+	var result *UploadForm
+	err := d.call(context.Background(), "GenerateUploadForm", func() (err error) {
+		result, err = d.inner.GenerateUploadForm(keyPrefix, maxSize, allowedTypes, expiry)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UploadNDJSON(path string, records <-chan interface{}) error {
+	return d.call(context.Background(), "UploadNDJSON", func() error {
+		return d.inner.UploadNDJSON(path, records)
+	})
+}
+
+func (d *decorator) UploadWriter(path, contentType string) (io.WriteCloser, error) {
+	var result io.WriteCloser
+	err := d.call(context.Background(), "UploadWriter", func() (err error) {
+		result, err = d.inner.UploadWriter(path, contentType)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UploadJSONFileWithLink(path string, data io.Reader, linkExpiration time.Duration) (*url.URL, error) {
+	var result *url.URL
+	err := d.call(context.Background(), "UploadJSONFileWithLink", func() (err error) {
+		result, err = d.inner.UploadJSONFileWithLink(path, data, linkExpiration)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) AppendToObject(path string, data io.Reader) error {
+	return d.call(context.Background(), "AppendToObject", func() error {
+		return d.inner.AppendToObject(path, data)
+	})
+}
+
+func (d *decorator) DownloadFile(path, localPath string, opts ...DownloadOption) error {
+	return d.call(context.Background(), "DownloadFile", func() error {
+		return d.inner.DownloadFile(path, localPath, opts...)
+	})
+}
+
+func (d *decorator) DownloadFileParallel(path, localPath string, parts int) error {
+	return d.call(context.Background(), "DownloadFileParallel", func() error {
+		return d.inner.DownloadFileParallel(path, localPath, parts)
+	})
+}
+
+func (d *decorator) DownloadResume(path, localPath string) error {
+	return d.call(context.Background(), "DownloadResume", func() error {
+		return d.inner.DownloadResume(path, localPath)
+	})
+}
+
+func (d *decorator) DrainFile(path, localPath string) error {
+	return d.call(context.Background(), "DrainFile", func() error {
+		return d.inner.DrainFile(path, localPath)
+	})
+}
+
+func (d *decorator) DownloadIfModified(path, localPath string, since time.Time) (bool, error) {
+	var result bool
+	err := d.call(context.Background(), "DownloadIfModified", func() (err error) {
+		result, err = d.inner.DownloadIfModified(path, localPath, since)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadIfETagDiffers(path, localPath, etag string) (bool, error) {
+	var result bool
+	err := d.call(context.Background(), "DownloadIfETagDiffers", func() (err error) {
+		result, err = d.inner.DownloadIfETagDiffers(path, localPath, etag)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadDirectory(path, localPath string, opts ...DirectoryOption) error {
+	return d.call(context.Background(), "DownloadDirectory", func() error {
+		return d.inner.DownloadDirectory(path, localPath, opts...)
+	})
+}
+
+func (d *decorator) DownloadDirectoryWithResult(path, localPath string, opts ...DirectoryOption) (*DownloadResult, error) {
+	var result *DownloadResult
+	err := d.call(context.Background(), "DownloadDirectoryWithResult", func() (err error) {
+		result, err = d.inner.DownloadDirectoryWithResult(path, localPath, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UploadDirectory(localPath, path string, opts ...DirectoryOption) error {
+	return d.call(context.Background(), "UploadDirectory", func() error {
+		return d.inner.UploadDirectory(localPath, path, opts...)
+	})
+}
+
+func (d *decorator) DownloadFileBytes(path string) ([]byte, error) {
+	var result []byte
+	err := d.call(context.Background(), "DownloadFileBytes", func() (err error) {
+		result, err = d.inner.DownloadFileBytes(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadTo(path string, w io.Writer) (int64, error) {
+	var result int64
+	err := d.call(context.Background(), "DownloadTo", func() (err error) {
+		result, err = d.inner.DownloadTo(path, w)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadStreamVerified(path string) (io.ReadCloser, error) {
+	var result io.ReadCloser
+	err := d.call(context.Background(), "DownloadStreamVerified", func() (err error) {
+		result, err = d.inner.DownloadStreamVerified(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadFileBuffer(path string) (*Buffer, error) {
+	var result *Buffer
+	err := d.call(context.Background(), "DownloadFileBuffer", func() (err error) {
+		result, err = d.inner.DownloadFileBuffer(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadFileMmap(path string) (*MmapHandle, error) {
+	var result *MmapHandle
+	err := d.call(context.Background(), "DownloadFileMmap", func() (err error) {
+		result, err = d.inner.DownloadFileMmap(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadVersionBytes(path, versionID string) ([]byte, error) {
+	var result []byte
+	err := d.call(context.Background(), "DownloadVersionBytes", func() (err error) {
+		result, err = d.inner.DownloadVersionBytes(path, versionID)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) DownloadVersionRange(path, versionID string, offset, length int64) ([]byte, error) {
+	var result []byte
+	err := d.call(context.Background(), "DownloadVersionRange", func() (err error) {
+		result, err = d.inner.DownloadVersionRange(path, versionID, offset, length)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) ListObjects(prefix string) ([]ObjectInfo, error) {
+	var result []ObjectInfo
+	err := d.call(context.Background(), "ListObjects", func() (err error) {
+		result, err = d.inner.ListObjects(prefix)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) IterateObjects(ctx context.Context, prefix string) *ObjectIterator {
+	return d.inner.IterateObjects(ctx, prefix)
+}
+
+func (d *decorator) ForEachObject(ctx context.Context, prefix string, recursive bool, fn func(ObjectInfo) error) error {
+	return d.call(ctx, "ForEachObject", func() error {
+		return d.inner.ForEachObject(ctx, prefix, recursive, fn)
+	})
+}
+
+func (d *decorator) ListDir(prefix string) (*DirListing, error) {
+	var result *DirListing
+	err := d.call(context.Background(), "ListDir", func() (err error) {
+		result, err = d.inner.ListDir(prefix)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) FileExists(path string) (bool, error) {
+	var result bool
+	err := d.call(context.Background(), "FileExists", func() (err error) {
+		result, err = d.inner.FileExists(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) FilesExist(paths []string) (map[string]bool, error) {
+	var result map[string]bool
+	err := d.call(context.Background(), "FilesExist", func() (err error) {
+		result, err = d.inner.FilesExist(paths)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) WaitForObject(ctx context.Context, path string, pollInterval time.Duration) error {
+	return d.call(ctx, "WaitForObject", func() error {
+		return d.inner.WaitForObject(ctx, path, pollInterval)
+	})
+}
+
+func (d *decorator) CopyFile(srcPath, dstPath string, opts ...CopyOption) error {
+	return d.call(context.Background(), "CopyFile", func() error {
+		return d.inner.CopyFile(srcPath, dstPath, opts...)
+	})
+}
+
+func (d *decorator) CopyToBucket(srcPath, dstBucket, dstPath string, opts ...CopyOption) error {
+	return d.call(context.Background(), "CopyToBucket", func() error {
+		return d.inner.CopyToBucket(srcPath, dstBucket, dstPath, opts...)
+	})
+}
+
+func (d *decorator) CopyDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error {
+	return d.call(context.Background(), "CopyDirectory", func() error {
+		return d.inner.CopyDirectory(srcPrefix, dstPrefix, opts...)
+	})
+}
+
+func (d *decorator) MoveDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error {
+	return d.call(context.Background(), "MoveDirectory", func() error {
+		return d.inner.MoveDirectory(srcPrefix, dstPrefix, opts...)
+	})
+}
+
+func (d *decorator) CopyChanged(srcPrefix, dstPrefix string, opts ...DirectoryOption) (*CopyResult, error) {
+	var result *CopyResult
+	err := d.call(context.Background(), "CopyChanged", func() (err error) {
+		result, err = d.inner.CopyChanged(srcPrefix, dstPrefix, opts...)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) UpdateMetadata(path string, metadata map[string]string, contentType string) error {
+	return d.call(context.Background(), "UpdateMetadata", func() error {
+		return d.inner.UpdateMetadata(path, metadata, contentType)
+	})
+}
+
+func (d *decorator) FixContentTypes(prefix string) (int, error) {
+	var result int
+	err := d.call(context.Background(), "FixContentTypes", func() (err error) {
+		result, err = d.inner.FixContentTypes(prefix)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) StatFile(path string) (*FileInfo, error) {
+	var result *FileInfo
+	err := d.call(context.Background(), "StatFile", func() (err error) {
+		result, err = d.inner.StatFile(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) GetWebsiteRedirect(path string) (string, error) {
+	var result string
+	err := d.call(context.Background(), "GetWebsiteRedirect", func() (err error) {
+		result, err = d.inner.GetWebsiteRedirect(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) CompareMetadata(pathA, pathB string) (*MetadataDiff, error) {
+	var result *MetadataDiff
+	err := d.call(context.Background(), "CompareMetadata", func() (err error) {
+		result, err = d.inner.CompareMetadata(pathA, pathB)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) GetObjectACL(path string) (*ObjectACL, error) {
+	var result *ObjectACL
+	err := d.call(context.Background(), "GetObjectACL", func() (err error) {
+		result, err = d.inner.GetObjectACL(path)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) SetObjectACL(path, acl string) error {
+	return d.call(context.Background(), "SetObjectACL", func() error {
+		return d.inner.SetObjectACL(path, acl)
+	})
+}
+
+func (d *decorator) Touch(path string) error {
+	return d.call(context.Background(), "Touch", func() error {
+		return d.inner.Touch(path)
+	})
+}
+
+func (d *decorator) TagPrefix(prefix string, tags map[string]string) error {
+	return d.call(context.Background(), "TagPrefix", func() error {
+		return d.inner.TagPrefix(prefix, tags)
+	})
+}
+
+func (d *decorator) FindByTag(prefix, tagKey, tagValue string) ([]string, error) {
+	var result []string
+	err := d.call(context.Background(), "FindByTag", func() (err error) {
+		result, err = d.inner.FindByTag(prefix, tagKey, tagValue)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) RemoveFile(path string) error {
+	return d.call(context.Background(), "RemoveFile", func() error {
+		return d.inner.RemoveFile(path)
+	})
+}
+
+func (d *decorator) SoftDelete(path string) error {
+	return d.call(context.Background(), "SoftDelete", func() error {
+		return d.inner.SoftDelete(path)
+	})
+}
+
+func (d *decorator) Undelete(path string) error {
+	return d.call(context.Background(), "Undelete", func() error {
+		return d.inner.Undelete(path)
+	})
+}
+
+func (d *decorator) RemoveFileIfMatch(path, etag string) error {
+	return d.call(context.Background(), "RemoveFileIfMatch", func() error {
+		return d.inner.RemoveFileIfMatch(path, etag)
+	})
+}
+
+func (d *decorator) RemoveFiles(paths []string, opts ...RemoveOption) error {
+	return d.call(context.Background(), "RemoveFiles", func() error {
+		return d.inner.RemoveFiles(paths, opts...)
+	})
+}
+
+func (d *decorator) RemoveMatching(prefix, pattern string) ([]string, error) {
+	var result []string
+	err := d.call(context.Background(), "RemoveMatching", func() (err error) {
+		result, err = d.inner.RemoveMatching(prefix, pattern)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) EmptyTrash(olderThan time.Duration) (int, error) {
+	var result int
+	err := d.call(context.Background(), "EmptyTrash", func() (err error) {
+		result, err = d.inner.EmptyTrash(olderThan)
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) BucketUsage() (*Usage, error) {
+	var result *Usage
+	err := d.call(context.Background(), "BucketUsage", func() (err error) {
+		result, err = d.inner.BucketUsage()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) BucketStatus() (*BucketStatus, error) {
+	var result *BucketStatus
+	err := d.call(context.Background(), "BucketStatus", func() (err error) {
+		result, err = d.inner.BucketStatus()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) ExportManifest(prefix string, w io.Writer) error {
+	return d.call(context.Background(), "ExportManifest", func() error {
+		return d.inner.ExportManifest(prefix, w)
+	})
+}
+
+func (d *decorator) DownloadManifest(manifestPath, localRoot string) error {
+	return d.call(context.Background(), "DownloadManifest", func() error {
+		return d.inner.DownloadManifest(manifestPath, localRoot)
+	})
+}
+
+func (d *decorator) GetBucketRegion() (string, error) {
+	var result string
+	err := d.call(context.Background(), "GetBucketRegion", func() (err error) {
+		result, err = d.inner.GetBucketRegion()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) ListBuckets() ([]BucketInfo, error) {
+	var result []BucketInfo
+	err := d.call(context.Background(), "ListBuckets", func() (err error) {
+		result, err = d.inner.ListBuckets()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) Raw() *minio.Client {
+	return d.inner.Raw()
+}
+
+func (d *decorator) SetDefaultRetention(mode RetentionMode, days int) error {
+	return d.call(context.Background(), "SetDefaultRetention", func() error {
+		return d.inner.SetDefaultRetention(mode, days)
+	})
+}
+
+func (d *decorator) GetDefaultRetention() (*DefaultRetention, error) {
+	var result *DefaultRetention
+	err := d.call(context.Background(), "GetDefaultRetention", func() (err error) {
+		result, err = d.inner.GetDefaultRetention()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) SetReplication(config ReplicationConfig) error {
+	return d.call(context.Background(), "SetReplication", func() error {
+		return d.inner.SetReplication(config)
+	})
+}
+
+func (d *decorator) GetReplication() (*ReplicationConfig, error) {
+	var result *ReplicationConfig
+	err := d.call(context.Background(), "GetReplication", func() (err error) {
+		result, err = d.inner.GetReplication()
+		return err
+	})
+	return result, err
+}
+
+func (d *decorator) Reconfigure(accessKey, secret, token string, verify bool) error {
+	return d.call(context.Background(), "Reconfigure", func() error {
+		return d.inner.Reconfigure(accessKey, secret, token, verify)
+	})
+}
@@ -0,0 +1,418 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// WithTempDir makes DownloadFile write the data to a temporary file in dir before moving it to
+// the destination, instead of next to the destination. If dir is on another file system than
+// the destination, the file is copied, so the destination is no longer replaced atomically.
+func WithTempDir(dir string) Option {
+	return func(o *options) {
+		o.tempDir = dir
+	}
+}
+
+// getObjectViaTemp downloads path to a temporary file in the configured temp dir and moves it
+// to localPath once it is complete.
+func (s *service) getObjectViaTemp(ctx context.Context, path, localPath string, opts minio.GetObjectOptions) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0777); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(s.opts.tempDir, filepath.Base(localPath)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), opts)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	defer object.Close()
+	if _, err := io.Copy(tmp, object); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return moveFile(tmp.Name(), localPath)
+}
+
+// moveFile renames src to dst, copying it if they are on different file systems.
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// DownloadTo copies the object to w and returns the number of bytes written. Failed requests
+// are only retried as long as nothing was written to w.
+func (s *service) DownloadTo(path string, w io.Writer) (int64, error) {
+	var written int64
+	var copyErr error
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+		written, copyErr = io.Copy(w, object)
+		if written == 0 {
+			return copyErr
+		}
+		// w already received data, a retry would write it twice
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, s.wrapError(copyErr)
+}
+
+// DrainFile downloads path to localPath and removes the object once the file is completely
+// written and matches the object's checksum, e.g. to consume objects used as messages at least
+// once. If the download fails the object is kept for a retry. If the object was replaced during
+// the download, the new version is kept and a *PreconditionFailedError returned, the file then
+// holds the old version. It isn't a lock: concurrent consumers may both get the same object.
+func (s *service) DrainFile(path, localPath string) error {
+	var info ObjectInfo
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		info, err = s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s: %v", ErrNotFound, path, err)
+		}
+		return s.wrapError(err)
+	}
+	opts := []DownloadOption{WithIfMatch(info.ETag), WithVerifyChecksum()}
+	if err := s.downloadFile(context.Background(), path, localPath, opts); err != nil {
+		return err
+	}
+	return s.RemoveFileIfMatch(path, info.ETag)
+}
+
+// DownloadFileParallel downloads the object in parts concurrent ranged requests. All ranges are
+// requested with the ETag of the object, so a concurrent overwrite fails the download instead
+// of mixing two versions. The file is created at the full size and every range written at its
+// offset, in whatever order they finish. If a range fails the partial file is removed.
+func (s *service) DownloadFileParallel(path, localPath string, parts int) (err error) {
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	if err != nil {
+		return s.wrapError(err)
+	}
+	if parts < 1 {
+		parts = 1
+	}
+	if int64(parts) > info.Size {
+		parts = int(info.Size)
+	}
+	if parts <= 1 {
+		return s.DownloadFile(path, localPath)
+	}
+	if dir := filepath.Dir(localPath); dir != "" {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+	}
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(localPath)
+		}
+	}()
+	if err := file.Truncate(info.Size); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	partSize := info.Size / int64(parts)
+	var total int64
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, parts)
+	for i := 0; i < parts; i++ {
+		start := int64(i) * partSize
+		end := start + partSize - 1
+		if i == parts-1 {
+			end = info.Size - 1
+		}
+		wg.Add(1)
+		go func(start, end int64) {
+			defer wg.Done()
+			written, err := s.downloadRange(ctx, path, info.ETag, file, start, end)
+			if err == nil && written != end-start+1 {
+				err = fmt.Errorf("downloaded %d bytes of range %d-%d of %s", written, start, end, path)
+			}
+			if err != nil {
+				errCh <- err
+				cancel()
+				return
+			}
+			atomic.AddInt64(&total, written)
+		}(start, end)
+	}
+	wg.Wait()
+	close(errCh)
+	if err := <-errCh; err != nil {
+		return err
+	}
+	if total != info.Size {
+		return fmt.Errorf("downloaded %d bytes of %s but the object has %d bytes", total, path, info.Size)
+	}
+	return file.Sync()
+}
+
+// downloadRange writes the bytes start to end (inclusive) of the object to the same offset in
+// file and returns how many bytes were written. The ETag condition makes sure all ranges are
+// read from the same version of the object.
+func (s *service) downloadRange(ctx context.Context, path, etag string, file io.WriterAt, start, end int64) (int64, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(start, end); err != nil {
+		return 0, err
+	}
+	if err := opts.SetMatchETag(etag); err != nil {
+		return 0, err
+	}
+	var written int64
+	err := s.retry(ctx, func(ctx context.Context) error {
+		object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), opts)
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+		// a retry rewrites the range from its start
+		written, err = io.Copy(&offsetWriter{w: file, offset: start}, object)
+		return err
+	})
+	return written, err
+}
+
+// offsetWriter writes sequentially to w starting at offset.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// DownloadResume continues an interrupted download by requesting only the bytes missing from
+// localPath. While the download is incomplete the object's ETag is kept in localPath+".etag";
+// if it no longer matches the object the download starts over.
+func (s *service) DownloadResume(path, localPath string) error {
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	if err != nil {
+		return s.wrapError(err)
+	}
+	etagPath := localPath + ".etag"
+	offset := int64(0)
+	if stat, err := os.Stat(localPath); err == nil {
+		etag, err := ioutil.ReadFile(etagPath)
+		switch {
+		case os.IsNotExist(err) && stat.Size() == info.Size:
+			// completed earlier
+			return nil
+		case err == nil && string(etag) == info.ETag && stat.Size() == info.Size:
+			return os.Remove(etagPath)
+		case err == nil && string(etag) == info.ETag && stat.Size() < info.Size:
+			offset = stat.Size()
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if info.Size == 0 {
+		return s.DownloadFile(path, localPath)
+	}
+	if dir := filepath.Dir(localPath); dir != "" {
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(etagPath, []byte(info.ETag), 0600); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Truncate(offset); err != nil {
+		return err
+	}
+	if _, err := s.downloadRange(context.Background(), path, info.ETag, file, offset, info.Size-1); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Remove(etagPath)
+}
+
+// ChecksumMismatchError is returned when a downloaded file doesn't match its object.
+type ChecksumMismatchError struct {
+	Key      string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum of downloaded %s is %s, expected %s", e.Key, e.Actual, e.Expected)
+}
+
+// SHA256MetadataKey is the user metadata field (x-amz-meta-sha256) DownloadStreamVerified
+// checks the data against. It holds the hex encoded SHA256 of the object and has to be set by
+// the uploader, e.g. with UserMetadata of UploadFileWithOptions.
+const SHA256MetadataKey = "Sha256"
+
+// verifiedReader hashes an object while it's read and compares the hash at EOF.
+type verifiedReader struct {
+	object   *minio.Object
+	hash     hash.Hash
+	key      string
+	expected string
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.object.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF {
+		if sum := hex.EncodeToString(r.hash.Sum(nil)); !strings.EqualFold(sum, r.expected) {
+			return n, &ChecksumMismatchError{Key: r.key, Expected: r.expected, Actual: sum}
+		}
+	}
+	return n, err
+}
+
+func (r *verifiedReader) Close() error {
+	return r.object.Close()
+}
+
+// DownloadStreamVerified returns a reader of the object which verifies the data once it's read
+// completely: the last Read returns a *ChecksumMismatchError instead of io.EOF if the data
+// doesn't match. The SHA256 in SHA256MetadataKey is used if present, otherwise the MD5 of
+// single part uploads (ETag) not encrypted with SSE-KMS or SSE-C. Objects with neither can't be
+// verified and return an error.
+func (s *service) DownloadStreamVerified(path string) (io.ReadCloser, error) {
+	var reader *verifiedReader
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		info, err := object.Stat()
+		if err != nil {
+			object.Close()
+			return err
+		}
+		reader = &verifiedReader{object: object, key: path}
+		if sum := info.Metadata.Get("X-Amz-Meta-" + SHA256MetadataKey); sum != "" {
+			reader.hash, reader.expected = sha256.New(), sum
+		} else if etag, ok := md5ETag(info); ok {
+			reader.hash, reader.expected = md5.New(), etag
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reader.hash == nil {
+		reader.Close()
+		return nil, fmt.Errorf("%s has no checksum to verify against, it was uploaded in parts or with SSE-KMS or SSE-C and without %s metadata", path, SHA256MetadataKey)
+	}
+	return reader, nil
+}
+
+// md5ETag returns the ETag of the object if it is the MD5 of the data. That isn't the case for
+// multipart uploads ("<md5 of part md5s>-<parts>") and for objects encrypted with SSE-KMS or
+// SSE-C.
+func md5ETag(info ObjectInfo) (string, bool) {
+	if info.Metadata.Get("X-Amz-Server-Side-Encryption") == "aws:kms" ||
+		info.Metadata.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != "" {
+		return "", false
+	}
+	etag := strings.Trim(info.ETag, "\"")
+	if len(etag) != 32 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(etag); err != nil {
+		return "", false
+	}
+	return etag, true
+}
+
+func verifyFile(localPath string, info ObjectInfo) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	etag, ok := md5ETag(info)
+	if !ok {
+		stat, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if stat.Size() != info.Size {
+			return &ChecksumMismatchError{
+				Key:      info.Key,
+				Expected: fmt.Sprintf("%d bytes", info.Size),
+				Actual:   fmt.Sprintf("%d bytes", stat.Size()),
+			}
+		}
+		return nil
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(sum, etag) {
+		return &ChecksumMismatchError{Key: info.Key, Expected: etag, Actual: sum}
+	}
+	return nil
+}
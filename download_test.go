@@ -0,0 +1,31 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMD5ETag(t *testing.T) {
+	const sum = "5d41402abc4b2a76b9719d911017c592"
+	header := func(key, value string) http.Header {
+		h := http.Header{}
+		h.Set(key, value)
+		return h
+	}
+	tests := []struct {
+		name string
+		info ObjectInfo
+		want bool
+	}{
+		{"single part", ObjectInfo{ETag: `"` + sum + `"`}, true},
+		{"SSE-S3", ObjectInfo{ETag: sum, Metadata: header("X-Amz-Server-Side-Encryption", "AES256")}, true},
+		{"multipart", ObjectInfo{ETag: `"` + sum + `-3"`}, false},
+		{"SSE-KMS", ObjectInfo{ETag: sum, Metadata: header("X-Amz-Server-Side-Encryption", "aws:kms")}, false},
+		{"SSE-C", ObjectInfo{ETag: sum, Metadata: header("X-Amz-Server-Side-Encryption-Customer-Algorithm", "AES256")}, false},
+	}
+	for _, test := range tests {
+		if etag, ok := md5ETag(test.info); ok != test.want || (ok && etag != sum) {
+			t.Errorf("%s: md5ETag = %q, %v, want %v", test.name, etag, ok, test.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/minio/minio-go/v6/pkg/encrypt"
+)
+
+const (
+	kmsKeyIDHeader             = "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"
+	encryptionContextHeader    = "X-Amz-Server-Side-Encryption-Context"
+	serverSideEncryptionHeader = "X-Amz-Server-Side-Encryption"
+)
+
+// kmsEncryption is SSE-KMS with an encryption context. encrypt.NewSSEKMS sends the context in
+// a header S3 doesn't know, so the headers are set here.
+type kmsEncryption struct {
+	keyID   string
+	context map[string]string
+}
+
+func (e kmsEncryption) Type() encrypt.Type { return encrypt.KMS }
+
+func (e kmsEncryption) Marshal(h http.Header) {
+	h.Set(serverSideEncryptionHeader, "aws:kms")
+	if e.keyID != "" {
+		h.Set(kmsKeyIDHeader, e.keyID)
+	}
+	if len(e.context) > 0 {
+		// marshalling a map[string]string can't fail
+		data, _ := json.Marshal(e.context)
+		h.Set(encryptionContextHeader, base64.StdEncoding.EncodeToString(data))
+	}
+}
+
+// WithKMSEncryption encrypts the uploaded object with the KMS key keyID, or with the default
+// key of the bucket if keyID is empty. The encryption context is bound to the encryption key,
+// so key policies can grant access based on it, and shows up in the audit logs of KMS.
+func WithKMSEncryption(keyID string, context map[string]string) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.ServerSideEncryption = kmsEncryption{keyID: keyID, context: context}
+	}
+}
+
+// checkEncryptionContext returns an error wrapping ErrEncryptionContextMismatch unless the
+// object described by header was encrypted with exactly context.
+func checkEncryptionContext(path string, header http.Header, context map[string]string) error {
+	stored := map[string]string{}
+	if value := header.Get(encryptionContextHeader); value != "" {
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("s3 invalid encryption context of %s: %v", path, err)
+		}
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return fmt.Errorf("s3 invalid encryption context of %s: %v", path, err)
+		}
+	}
+	if header.Get(serverSideEncryptionHeader) != "aws:kms" || !reflect.DeepEqual(stored, context) {
+		return fmt.Errorf("%w: %s", ErrEncryptionContextMismatch, path)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+// ErrNotFound is returned when an object doesn't exist.
+var ErrNotFound = errors.New("s3 object not found")
+
+// ErrPreconditionFailed is matched by errors.Is when the condition of a conditional operation
+// didn't hold, such as the ETag of RemoveFileIfMatch.
+var ErrPreconditionFailed = errors.New("s3 precondition failed")
+
+// PreconditionFailedError is returned when a conditional operation wasn't done because the
+// object changed. Condition describes what didn't match.
+type PreconditionFailedError struct {
+	Key       string
+	Condition string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	return fmt.Sprintf("s3 precondition failed for %s: %s", e.Key, e.Condition)
+}
+
+func (e *PreconditionFailedError) Is(target error) bool {
+	return target == ErrPreconditionFailed
+}
+
+// ErrEncryptionContextMismatch is returned by downloads with WithEncryptionContext if the object
+// isn't encrypted with SSE-KMS and the given context.
+var ErrEncryptionContextMismatch = errors.New("s3 object isn't encrypted with the expected context")
+
+// ErrCircuitOpen is returned by NewCircuitBreakerService instead of calling the service while
+// the circuit is open.
+var ErrCircuitOpen = errors.New("s3 circuit breaker is open after repeated failures")
+
+// Causes of a *StartupError, test for them with errors.Is.
+var (
+	ErrInvalidCredentials  = errors.New("s3 credentials are invalid or not allowed to access the bucket")
+	ErrEndpointUnreachable = errors.New("s3 endpoint is unreachable")
+	ErrBucketNotFound      = errors.New("s3 bucket not found")
+)
+
+// StartupError is returned by NewService if the bucket can't be accessed. It names the
+// endpoint, bucket and region that were tried, Kind is one of ErrInvalidCredentials,
+// ErrEndpointUnreachable and ErrBucketNotFound, or nil if the cause is unknown.
+type StartupError struct {
+	Endpoint  string
+	Bucket    string
+	Region    string
+	RequestID string
+	Kind      error
+	Err       error
+}
+
+func (e *StartupError) Error() string {
+	msg := fmt.Sprintf("s3 bucket (%s) at %s", e.Bucket, e.Endpoint)
+	if e.Region != "" {
+		msg += fmt.Sprintf(" in region %s", e.Region)
+	}
+	switch {
+	case e.Kind != nil && e.Err != nil:
+		msg += fmt.Sprintf(": %v: %v", e.Kind, e.Err)
+	case e.Kind != nil:
+		msg += fmt.Sprintf(": %v", e.Kind)
+	default:
+		msg += fmt.Sprintf(": %v", e.Err)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request id: %s)", e.RequestID)
+	}
+	return msg
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the Kind of the error.
+func (e *StartupError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// newStartupError classifies err returned while checking the bucket during NewService.
+func newStartupError(endpoint, bucket, region string, err error) *StartupError {
+	startupErr := &StartupError{Endpoint: endpoint, Bucket: bucket, Region: region, Err: err}
+	resp := errorResponse(err)
+	startupErr.RequestID = resp.RequestID
+	var netErr net.Error
+	switch {
+	case resp.Code == "InvalidAccessKeyId" || resp.Code == "SignatureDoesNotMatch" ||
+		resp.Code == "AccessDenied" || resp.StatusCode == http.StatusForbidden:
+		startupErr.Kind = ErrInvalidCredentials
+	case resp.Code == "NoSuchBucket":
+		startupErr.Kind = ErrBucketNotFound
+	case errors.As(err, &netErr):
+		startupErr.Kind = ErrEndpointUnreachable
+	}
+	return startupErr
+}
+
+// endpointRegion guesses the region from endpoints like obs.eu-de.otc.t-systems.com.
+func endpointRegion(host string) string {
+	if region := s3utils.GetRegionFromURL(url.URL{Host: host}); region != "" {
+		return region
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) > 2 && labels[0] == "obs" {
+		return labels[1]
+	}
+	return ""
+}
+
+// Error wraps an error response of GOBS together with the request IDs OTC support needs to
+// trace the request. Use errors.As to get the underlying minio.ErrorResponse.
+type Error struct {
+	Err       error
+	requestID string
+	hostID    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v (request id: %s, host id: %s)", e.Err, e.requestID, e.hostID)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RequestID returns the x-amz-request-id of the failed request.
+func (e *Error) RequestID() string {
+	return e.requestID
+}
+
+// HostID returns the x-amz-id-2 of the failed request.
+func (e *Error) HostID() string {
+	return e.hostID
+}
+
+// RequestID returns the request ID of err if it is or wraps an *Error.
+func RequestID(err error) string {
+	var s3Err *Error
+	if errors.As(err, &s3Err) {
+		return s3Err.requestID
+	}
+	return ""
+}
+
+// WithErrorLogging logs every failed operation including its request IDs.
+func WithErrorLogging() Option {
+	return func(o *options) {
+		o.logErrors = true
+	}
+}
+
+// wrapError attaches the request IDs of error responses to err.
+func (s *service) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var s3Err *Error
+	if errors.As(err, &s3Err) {
+		return err
+	}
+	resp := errorResponse(err)
+	if resp.RequestID == "" && resp.HostID == "" {
+		return err
+	}
+	if s.opts.logErrors {
+		s.logger.Printf("s3: request failed: %v (request id: %s, host id: %s)", err, resp.RequestID, resp.HostID)
+	}
+	return &Error{Err: err, requestID: resp.RequestID, hostID: resp.HostID}
+}
+
+// errorResponse is like minio.ToErrorResponse but also finds wrapped error responses.
+func errorResponse(err error) minio.ErrorResponse {
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		return resp
+	}
+	return minio.ErrorResponse{}
+}
+
+func isNotModified(err error) bool {
+	return errorResponse(err).StatusCode == http.StatusNotModified
+}
+
+// BatchError is returned by batch operations when some keys failed. Errors maps each failed
+// key to its error, so callers can retry just those keys. Err is the error that stopped the
+// operation before all objects were processed, e.g. a failed listing.
+type BatchError struct {
+	Op     string
+	Errors map[string]error
+	Err    error
+}
+
+func (e *BatchError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	msgs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", key, e.Errors[key]))
+	}
+	msg := fmt.Sprintf("Failed to %s %d files from s3: %s", e.Op, len(e.Errors), strings.Join(msgs, "; "))
+	if e.Err != nil {
+		msg += fmt.Sprintf(" (stopped: %v)", e.Err)
+	}
+	return msg
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Failures returns the failed keys of a batch operation, or nil if err is not a *BatchError.
+func Failures(err error) map[string]error {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Errors
+	}
+	return nil
+}
+
+func newBatchError(op string, errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &BatchError{Op: op, Errors: errs}
+}
+
+func isNotFound(err error) bool {
+	resp := errorResponse(err)
+	return resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey"
+}
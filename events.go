@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"time"
+)
+
+// EventOp is the kind of mutating operation an Event reports.
+type EventOp string
+
+const (
+	EventUpload       EventOp = "upload"
+	EventAppend       EventOp = "append"
+	EventDelete       EventOp = "delete"
+	EventUndelete     EventOp = "undelete"
+	EventCopy         EventOp = "copy"
+	EventMetadata     EventOp = "metadata"
+	EventTag          EventOp = "tag"
+	EventACL          EventOp = "acl"
+	EventLifecycle    EventOp = "lifecycle"
+	EventBucketConfig EventOp = "bucket-config"
+)
+
+// Event describes a finished mutating operation. Key is the affected object, or the rule ID
+// for lifecycle changes; copies also name the Source and, for other buckets, the Bucket of the
+// destination. Err is nil if the operation succeeded.
+type Event struct {
+	Op       EventOp
+	Bucket   string
+	Key      string
+	Source   string
+	Size     int64
+	Err      error
+	Duration time.Duration
+}
+
+// WithEventHook calls hook after every mutating operation, e.g. to write an audit log. The hook
+// is called synchronously by the goroutine doing the operation, so a slow hook slows down the
+// service; hand events off to a channel if they need expensive processing.
+func WithEventHook(hook func(Event)) Option {
+	return func(o *options) {
+		o.eventHook = hook
+	}
+}
+
+// emit reports a finished operation that started at start to the event hook.
+func (s *service) emit(event Event, start time.Time) {
+	if s.opts.eventHook == nil {
+		return
+	}
+	if event.Bucket == "" {
+		event.Bucket = s.bucketName
+	}
+	event.Duration = time.Since(start)
+	s.opts.eventHook(event)
+}
@@ -0,0 +1,704 @@
+package s3
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+const (
+	fakeBucket    = "test-bucket"
+	fakeAccessKey = "access"
+	fakeSecretKey = "secret"
+	fakeRegion    = "us-east-1"
+)
+
+// fakeS3 is an in-memory S3 endpoint with a single bucket, implementing the requests the tests
+// need. Signatures are only checked for presigned URLs.
+type fakeS3 struct {
+	*httptest.Server
+	t *testing.T
+
+	mu        sync.Mutex
+	objects   map[string]*fakeObject
+	uploads   map[string]*fakeUpload
+	lifecycle []byte
+	nextID    int
+	requests  []string
+	// fail is called for every request before it is handled, a non-zero status fails the
+	// request with a server error, or AccessDenied for 403.
+	fail func(r *http.Request) int
+	// pageSize limits the keys of a listing page, 0 means no limit.
+	pageSize int
+}
+
+type fakeObject struct {
+	data   []byte
+	header http.Header
+}
+
+type fakeUpload struct {
+	key    string
+	header http.Header
+	parts  map[int][]byte
+}
+
+func newFakeS3(t *testing.T) *fakeS3 {
+	f := &fakeS3{t: t, objects: map[string]*fakeObject{}, uploads: map[string]*fakeUpload{}}
+	f.Server = httptest.NewServer(f)
+	return f
+}
+
+func newFakeS3TLS(t *testing.T) *fakeS3 {
+	f := &fakeS3{t: t, objects: map[string]*fakeObject{}, uploads: map[string]*fakeUpload{}}
+	f.Server = httptest.NewTLSServer(f)
+	return f
+}
+
+// newService returns a service for the bucket of f.
+func (f *fakeS3) newService(opts ...Option) Service {
+	f.t.Helper()
+	opts = append([]Option{WithPlainHTTP()}, opts...)
+	service, err := NewService(f.URL, fakeAccessKey, fakeSecretKey, fakeBucket, opts...)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return service
+}
+
+func (f *fakeS3) put(key string, data []byte, header http.Header) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("ETag", etag(data))
+	f.objects[key] = &fakeObject{data: data, header: header}
+}
+
+func (f *fakeS3) object(key string) (*fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	obj, ok := f.objects[key]
+	return obj, ok
+}
+
+func (f *fakeS3) incompleteUploads() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.uploads)
+}
+
+func (f *fakeS3) requestLog() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	fail := f.fail
+	f.mu.Unlock()
+	if fail != nil {
+		if status := fail(r); status != 0 {
+			ioutil.ReadAll(r.Body)
+			code := "InternalError"
+			if status == http.StatusForbidden {
+				code = "AccessDenied"
+			}
+			writeError(w, status, code, "injected failure")
+			return
+		}
+	}
+	if r.URL.Query().Get("X-Amz-Signature") != "" {
+		if err := verifyPresigned(r); err != nil {
+			writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+			return
+		}
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	bucket, key := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		bucket, key = path[:i], path[i+1:]
+	}
+	if bucket != fakeBucket {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist")
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if key == "" {
+		f.serveBucket(w, r, body)
+	} else {
+		f.serveObject(w, r, key, body)
+	}
+}
+
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, body []byte) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case has(query, "location"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"LocationConstraint"`
+			Region  string   `xml:",chardata"`
+		}{Region: fakeRegion})
+	case has(query, "lifecycle") && r.Method == http.MethodPut:
+		f.lifecycle = body
+	case has(query, "lifecycle") && r.Method == http.MethodDelete:
+		f.lifecycle = nil
+		w.WriteHeader(http.StatusNoContent)
+	case has(query, "lifecycle"):
+		if f.lifecycle == nil {
+			writeError(w, http.StatusNotFound, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist")
+			return
+		}
+		w.Write(f.lifecycle)
+	case has(query, "versioning"):
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"VersioningConfiguration"`
+		}{})
+	case has(query, "object-lock"):
+		writeError(w, http.StatusNotFound, "ObjectLockConfigurationNotFoundError", "Object Lock configuration does not exist for this bucket")
+	case has(query, "uploads"):
+		f.listUploads(w, query)
+	case has(query, "delete"):
+		f.deleteObjects(w, body)
+	case r.Method == http.MethodGet:
+		f.listObjects(w, query)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.RequestURI())
+	}
+}
+
+func (f *fakeS3) listObjects(w http.ResponseWriter, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	token := query.Get("continuation-token")
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int64
+		StorageClass string
+	}
+	result := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Name                  string
+		Prefix                string
+		KeyCount              int
+		MaxKeys               int
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []content
+		CommonPrefixes        []struct{ Prefix string }
+	}{Name: fakeBucket, Prefix: prefix, MaxKeys: 1000}
+	seen := map[string]bool{}
+	for _, key := range f.sortedKeys() {
+		if !strings.HasPrefix(key, prefix) || key <= token {
+			continue
+		}
+		common := ""
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				common = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+		if !seen[common] && f.pageSize > 0 && len(result.Contents)+len(result.CommonPrefixes) == f.pageSize {
+			result.IsTruncated = true
+			break
+		}
+		// the token is the last key of the page, including the keys below common prefixes
+		result.NextContinuationToken = key
+		if common != "" {
+			if !seen[common] {
+				seen[common] = true
+				result.CommonPrefixes = append(result.CommonPrefixes, struct{ Prefix string }{common})
+			}
+			continue
+		}
+		obj := f.objects[key]
+		result.Contents = append(result.Contents, content{
+			Key:          key,
+			LastModified: time.Now().UTC().Format(time.RFC3339),
+			ETag:         obj.header.Get("ETag"),
+			Size:         int64(len(obj.data)),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+	writeXML(w, result)
+}
+
+func (f *fakeS3) sortedKeys() []string {
+	keys := make([]string, 0, len(f.objects))
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (f *fakeS3) listUploads(w http.ResponseWriter, query url.Values) {
+	type upload struct {
+		Key       string
+		UploadId  string
+		Initiated string
+	}
+	result := struct {
+		XMLName    xml.Name `xml:"ListMultipartUploadsResult"`
+		Bucket     string
+		Prefix     string
+		MaxUploads int
+		Upload     []upload
+	}{Bucket: fakeBucket, Prefix: query.Get("prefix"), MaxUploads: 1000}
+	ids := make([]string, 0, len(f.uploads))
+	for id := range f.uploads {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if u := f.uploads[id]; strings.HasPrefix(u.key, result.Prefix) {
+			result.Upload = append(result.Upload, upload{Key: u.key, UploadId: id, Initiated: time.Now().UTC().Format(time.RFC3339)})
+		}
+	}
+	writeXML(w, result)
+}
+
+func (f *fakeS3) deleteObjects(w http.ResponseWriter, body []byte) {
+	request := struct {
+		Object []struct{ Key string }
+	}{}
+	if err := xml.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+		return
+	}
+	result := struct {
+		XMLName xml.Name `xml:"DeleteResult"`
+		Deleted []struct{ Key string }
+	}{}
+	for _, obj := range request.Object {
+		delete(f.objects, obj.Key)
+		result.Deleted = append(result.Deleted, struct{ Key string }{obj.Key})
+	}
+	writeXML(w, result)
+}
+
+func (f *fakeS3) serveObject(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && has(query, "uploads"):
+		f.nextID++
+		id := "upload-" + strconv.Itoa(f.nextID)
+		f.uploads[id] = &fakeUpload{key: key, header: storedHeader(r.Header), parts: map[int][]byte{}}
+		writeXML(w, struct {
+			XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+		}{Bucket: fakeBucket, Key: key, UploadId: id})
+	case r.Method == http.MethodPost && has(query, "append"):
+		obj, ok := f.objects[key]
+		if !ok {
+			obj = &fakeObject{header: storedHeader(r.Header)}
+		}
+		if query.Get("position") != strconv.Itoa(len(obj.data)) {
+			writeError(w, http.StatusConflict, "PositionNotEqualToLength", "Position is not equal to the length of the object.")
+			return
+		}
+		obj.data = append(obj.data, body...)
+		obj.header.Set("ETag", etag(obj.data))
+		f.objects[key] = obj
+	case has(query, "uploadId"):
+		f.serveUpload(w, r, key, body)
+	case has(query, "acl"):
+		f.serveACL(w, r, key)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		data, src, ok := f.copySource(w, r)
+		if !ok {
+			return
+		}
+		header := src.header.Clone()
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			header = storedHeader(r.Header)
+		}
+		header.Set("ETag", etag(data))
+		f.objects[key] = &fakeObject{data: data, header: header}
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyObjectResult"`
+			ETag         string
+			LastModified string
+		}{ETag: etag(data), LastModified: time.Now().UTC().Format(time.RFC3339)})
+	case r.Method == http.MethodPut:
+		header := storedHeader(r.Header)
+		header.Set("ETag", etag(body))
+		f.objects[key] = &fakeObject{data: body, header: header}
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		obj, ok := f.objects[key]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		f.serveData(w, r, obj)
+	default:
+		writeError(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.RequestURI())
+	}
+}
+
+func (f *fakeS3) serveData(w http.ResponseWriter, r *http.Request, obj *fakeObject) {
+	for key, values := range obj.header {
+		w.Header()[key] = values
+	}
+	w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "binary/octet-stream")
+	}
+	data := obj.data
+	status := http.StatusOK
+	if spec := r.Header.Get("Range"); spec != "" {
+		start, end, ok := parseRange(spec, int64(len(data)))
+		if !ok {
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+			return
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+		data = data[start : end+1]
+		status = http.StatusPartialContent
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(status)
+	if r.Method == http.MethodGet {
+		w.Write(data)
+	}
+}
+
+func (f *fakeS3) serveACL(w http.ResponseWriter, r *http.Request, key string) {
+	obj, ok := f.objects[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	type grant struct {
+		Grantee struct {
+			ID   string `xml:"ID"`
+			URI  string `xml:"URI"`
+			Type string `xml:"xsi:type,attr"`
+		}
+		Permission string
+	}
+	policy := struct {
+		XMLName xml.Name `xml:"AccessControlPolicy"`
+		Owner   struct{ ID string }
+		Grant   []grant `xml:"AccessControlList>Grant"`
+	}{}
+	policy.Owner.ID = "owner"
+	owner := grant{Permission: "FULL_CONTROL"}
+	owner.Grantee.ID = "owner"
+	policy.Grant = append(policy.Grant, owner)
+	if obj.header.Get("X-Amz-Acl") == "public-read" {
+		public := grant{Permission: "READ"}
+		public.Grantee.URI = "http://acs.amazonaws.com/groups/global/AllUsers"
+		policy.Grant = append(policy.Grant, public)
+	}
+	writeXML(w, policy)
+}
+
+// copySource returns the data and the object named by the X-Amz-Copy-Source header of r,
+// restricted to X-Amz-Copy-Source-Range.
+func (f *fakeS3) copySource(w http.ResponseWriter, r *http.Request) ([]byte, *fakeObject, bool) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return nil, nil, false
+	}
+	source = strings.TrimPrefix(source, "/")
+	if i := strings.Index(source, "?"); i >= 0 {
+		source = source[:i]
+	}
+	obj, ok := f.objects[strings.TrimPrefix(source, fakeBucket+"/")]
+	if !ok || !strings.HasPrefix(source, fakeBucket+"/") {
+		writeError(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return nil, nil, false
+	}
+	data := obj.data
+	if spec := r.Header.Get("X-Amz-Copy-Source-Range"); spec != "" {
+		start, end, ok := parseRange(spec, int64(len(data)))
+		if !ok {
+			writeError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange", "The requested range is not satisfiable")
+			return nil, nil, false
+		}
+		data = data[start : end+1]
+	}
+	return append([]byte(nil), data...), obj, true
+}
+
+func (f *fakeS3) serveUpload(w http.ResponseWriter, r *http.Request, key string, body []byte) {
+	query := r.URL.Query()
+	id := query.Get("uploadId")
+	upload, ok := f.uploads[id]
+	if !ok || upload.key != key {
+		writeError(w, http.StatusNotFound, "NoSuchUpload", "The specified upload does not exist.")
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		number, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+			return
+		}
+		if r.Header.Get("X-Amz-Copy-Source") == "" {
+			upload.parts[number] = body
+			w.Header().Set("ETag", etag(body))
+			return
+		}
+		data, _, ok := f.copySource(w, r)
+		if !ok {
+			return
+		}
+		upload.parts[number] = data
+		writeXML(w, struct {
+			XMLName      xml.Name `xml:"CopyPartResult"`
+			ETag         string
+			LastModified string
+		}{ETag: etag(data), LastModified: time.Now().UTC().Format(time.RFC3339)})
+	case http.MethodPost:
+		complete := struct {
+			Part []struct{ PartNumber int }
+		}{}
+		if err := xml.Unmarshal(body, &complete); err != nil {
+			writeError(w, http.StatusBadRequest, "MalformedXML", err.Error())
+			return
+		}
+		var data []byte
+		for _, part := range complete.Part {
+			data = append(data, upload.parts[part.PartNumber]...)
+		}
+		header := upload.header
+		header.Set("ETag", fmt.Sprintf(`"%s-%d"`, strings.Trim(etag(data), `"`), len(complete.Part)))
+		f.objects[key] = &fakeObject{data: data, header: header}
+		delete(f.uploads, id)
+		writeXML(w, struct {
+			XMLName xml.Name `xml:"CompleteMultipartUploadResult"`
+			Bucket  string
+			Key     string
+			ETag    string
+		}{Bucket: fakeBucket, Key: key, ETag: header.Get("ETag")})
+	case http.MethodDelete:
+		delete(f.uploads, id)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		type part struct {
+			PartNumber   int
+			ETag         string
+			Size         int64
+			LastModified string
+		}
+		result := struct {
+			XMLName  xml.Name `xml:"ListPartsResult"`
+			Bucket   string
+			Key      string
+			UploadId string
+			MaxParts int
+			Part     []part
+		}{Bucket: fakeBucket, Key: key, UploadId: id, MaxParts: 1000}
+		numbers := make([]int, 0, len(upload.parts))
+		for number := range upload.parts {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		for _, number := range numbers {
+			data := upload.parts[number]
+			result.Part = append(result.Part, part{PartNumber: number, ETag: etag(data), Size: int64(len(data)), LastModified: time.Now().UTC().Format(time.RFC3339)})
+		}
+		writeXML(w, result)
+	}
+}
+
+// storedHeader returns the headers of a request S3 stores with the object.
+func storedHeader(header http.Header) http.Header {
+	stored := http.Header{}
+	for key, values := range header {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "x-amz-meta-") || lower == "x-amz-storage-class" || lower == "x-amz-acl" ||
+			strings.HasPrefix(lower, "x-amz-server-side-encryption") || isPreservedHeader(key) {
+			stored[key] = values
+		}
+	}
+	return stored
+}
+
+func has(query url.Values, key string) bool {
+	_, ok := query[key]
+	return ok
+}
+
+func parseRange(spec string, size int64) (int64, int64, bool) {
+	spec = strings.TrimPrefix(spec, "bytes=")
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	if parts[0] == "" {
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if parts[1] != "" {
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+// readBody reads the body of r, decoding the aws-chunked encoding minio-go uses for uploads
+// over plain HTTP.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		return ioutil.ReadAll(r.Body)
+	}
+	var data []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex := strings.TrimSpace(line)
+		if i := strings.Index(sizeHex, ";"); i >= 0 {
+			sizeHex = sizeHex[:i]
+		}
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		data = append(data, chunk[:size]...)
+	}
+}
+
+// verifyPresigned checks the signature of a presigned URL like S3 does, from the request as it
+// arrived.
+func verifyPresigned(r *http.Request) error {
+	query := r.URL.Query()
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+	date := query.Get("X-Amz-Date")
+	signed, err := time.Parse(amzDateFormat, date)
+	if err != nil {
+		return err
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil {
+		return err
+	}
+	if time.Now().After(signed.Add(time.Duration(expires) * time.Second)) {
+		return fmt.Errorf("Request has expired")
+	}
+	var headers []string
+	for _, name := range strings.Split(query.Get("X-Amz-SignedHeaders"), ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		headers = append(headers, name+":"+value+"\n")
+	}
+	canonical := strings.Join([]string{
+		r.Method,
+		s3utils.EncodePath(r.URL.Path),
+		strings.Replace(query.Encode(), "+", "%20", -1),
+		strings.Join(headers, ""),
+		query.Get("X-Amz-SignedHeaders"),
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	scope := date[:8] + "/" + fakeRegion + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + fakeSecretKey)
+	for _, part := range []string{date[:8], fakeRegion, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	if hex.EncodeToString(key) != signature {
+		return fmt.Errorf("The request signature we calculated does not match the signature you provided")
+	}
+	return nil
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(append([]byte(xml.Header), data...))
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	data, _ := xml.Marshal(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+		Message string
+	}{Code: code, Message: message})
+	w.Write(data)
+}
@@ -0,0 +1,112 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/signer"
+)
+
+// policyExpirationFormat is the format of the expiration of a POST policy.
+const policyExpirationFormat = "2006-01-02T15:04:05.000Z"
+
+// UploadForm holds everything a browser needs to upload a file directly with an HTML form:
+// the form's action URL and the fields that have to be sent along with the file.
+type UploadForm struct {
+	URL    *url.URL
+	Fields map[string]string
+}
+
+// GenerateUploadForm returns a form for browser uploads of 1 to maxSize bytes below keyPrefix,
+// valid for expiry by the clock set with WithClock. The key field lets the browser append the
+// name of the selected file. allowedTypes restricts the Content-Type field of the upload. A
+// single type is set as a field of the form. The conditions of a POST policy can't list
+// values, so several types are allowed through the prefix they share, e.g. "image/" for
+// image/png and image/jpeg, which admits other types with that prefix too; the page has to set
+// the field, usually to the type of the selected file. Types without a common prefix fail.
+func (s *service) GenerateUploadForm(keyPrefix string, maxSize int64, allowedTypes []string, expiry time.Duration) (*UploadForm, error) {
+	if s.opts.keyHash != nil {
+		return nil, errKeyHashing
+	}
+	key := s.key(keyPrefix)
+	if key == "" {
+		return nil, errors.New("s3 upload forms need a key prefix")
+	}
+	if maxSize < 1 {
+		return nil, errors.New("s3 upload forms need a maximum size of at least 1 byte")
+	}
+	creds, err := s.creds.Get()
+	if err != nil {
+		return nil, err
+	}
+	if creds.SignerType.IsAnonymous() {
+		return nil, errors.New("s3 upload forms can't be signed with anonymous credentials")
+	}
+	region, err := s.GetBucketRegion()
+	if err != nil {
+		return nil, err
+	}
+	now := s.now().UTC()
+	fields := map[string]string{
+		"bucket":                s.bucketName,
+		"key":                   key + "${filename}",
+		"success_action_status": "201",
+		"x-amz-algorithm":       "AWS4-HMAC-SHA256",
+		"x-amz-credential":      signer.GetCredential(creds.AccessKeyID, region, now, signer.ServiceTypeS3),
+		"x-amz-date":            now.Format(amzDateFormat),
+	}
+	if creds.SessionToken != "" {
+		fields["x-amz-security-token"] = creds.SessionToken
+	}
+	conditions := []interface{}{
+		[]string{"starts-with", "$key", key},
+		[]interface{}{"content-length-range", 1, maxSize},
+	}
+	switch len(allowedTypes) {
+	case 0:
+	case 1:
+		fields["Content-Type"] = allowedTypes[0]
+	default:
+		prefix := commonPrefix(allowedTypes)
+		if prefix == "" {
+			return nil, errors.New("s3 upload forms can only allow content types sharing a prefix")
+		}
+		conditions = append(conditions, []string{"starts-with", "$Content-Type", prefix})
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if name != "key" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		conditions = append(conditions, []string{"eq", "$" + name, fields[name]})
+	}
+	policy, err := json.Marshal(map[string]interface{}{
+		"expiration": now.Add(expiry).Format(policyExpirationFormat),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	fields["policy"] = base64.StdEncoding.EncodeToString(policy)
+	fields["x-amz-signature"] = signer.PostPresignSignatureV4(fields["policy"], now, creds.SecretAccessKey, region)
+	return &UploadForm{URL: s.objectURL(s.bucketName, ""), Fields: fields}, nil
+}
+
+// commonPrefix returns the longest prefix of all values.
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateUploadForm(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	service := fake.newService(WithRegion(fakeRegion), WithClock(func() time.Time { return now }))
+	tests := []struct {
+		types       []string
+		field       string
+		contentType []string
+	}{
+		{nil, "", nil},
+		{[]string{"image/png"}, "image/png", []string{"eq", "$Content-Type", "image/png"}},
+		{[]string{"image/png", "image/jpeg"}, "", []string{"starts-with", "$Content-Type", "image/"}},
+	}
+	for _, test := range tests {
+		form, err := service.GenerateUploadForm("uploads/", 1024, test.types, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := form.Fields["Content-Type"]; got != test.field {
+			t.Errorf("%v: Content-Type field is %q, want %q", test.types, got, test.field)
+		}
+		if got := form.Fields["x-amz-date"]; got != "20240301T120000Z" {
+			t.Errorf("%v: x-amz-date is %s, want the time of the clock", test.types, got)
+		}
+		data, err := base64.StdEncoding.DecodeString(form.Fields["policy"])
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy := struct {
+			Expiration string
+			Conditions []interface{}
+		}{}
+		if err := json.Unmarshal(data, &policy); err != nil {
+			t.Fatal(err)
+		}
+		if policy.Expiration != "2024-03-01T13:00:00.000Z" {
+			t.Errorf("%v: policy expires at %s", test.types, policy.Expiration)
+		}
+		var contentType []string
+		for _, condition := range policy.Conditions {
+			values, _ := condition.([]interface{})
+			if len(values) == 3 && values[1] == "$Content-Type" {
+				contentType = []string{values[0].(string), values[1].(string), values[2].(string)}
+			}
+		}
+		if !reflect.DeepEqual(contentType, test.contentType) {
+			t.Errorf("%v: content type condition is %v, want %v", test.types, contentType, test.contentType)
+		}
+	}
+	if _, err := service.GenerateUploadForm("uploads/", 1024, []string{"image/png", "text/plain"}, time.Hour); err == nil {
+		t.Error("form for types without a common prefix was generated")
+	}
+}
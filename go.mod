@@ -3,6 +3,7 @@ module github.com/MaxBreida/otc-gobs
 go 1.13
 
 require (
-	github.com/minio/minio-go/v6 v6.0.44
+	// v6.0.57 for the object tagging API (PutObjectTagging, GetObjectTagging) v6.0.44 lacks
+	github.com/minio/minio-go/v6 v6.0.57
 	golang.org/x/crypto v0.17.0 // indirect
 )
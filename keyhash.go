@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// maxKeyHashWidth is the most hex digits WithKeyHashing prepends. Listings need one request
+// per possible hash, 16^width.
+const maxKeyHashWidth = 3
+
+// errKeyHashing is returned by features selecting objects by a key prefix, which are spread
+// over all hash prefixes with WithKeyHashing.
+var errKeyHashing = errors.New("s3 key prefixes of lifecycle rules and upload forms can't be used with WithKeyHashing")
+
+// WithKeyHashing stores every object below the first width hex digits (1 to 3) of the hash of
+// its key, e.g. "3f/reports/2020.pdf", to spread the writes of keys sharing a prefix over
+// partitions. newHash creates the hash, md5.New if it's nil. Keys passed to and returned by the
+// service stay the logical ones. Listings request every possible hash prefix, 16^width lists,
+// and return the objects grouped by hash instead of sorted by key. Lifecycle rules and upload
+// forms for a prefix can't be used.
+//
+// This changes where objects are stored: objects uploaded without it, or with another width or
+// hash, aren't found and have to be migrated, e.g. with CopyToBucket.
+func WithKeyHashing(width int, newHash func() hash.Hash) Option {
+	return func(o *options) {
+		o.keyHashWidth = width
+		o.keyHash = newHash
+		if o.keyHash == nil {
+			o.keyHash = md5.New
+		}
+	}
+}
+
+func validKeyHashing(o *options) error {
+	if o.keyHash == nil {
+		return nil
+	}
+	if o.keyHashWidth < 1 || o.keyHashWidth > maxKeyHashWidth || o.keyHashWidth > 2*o.keyHash().Size() {
+		return fmt.Errorf("s3 key hash width must be between 1 and %d, got %d", maxKeyHashWidth, o.keyHashWidth)
+	}
+	return nil
+}
+
+// hashedKey prepends the hash prefix to a normalized key, see WithKeyHashing.
+func (o *options) hashedKey(key string) string {
+	if o.keyHash == nil {
+		return key
+	}
+	h := o.keyHash()
+	h.Write([]byte(key))
+	return hex.EncodeToString(h.Sum(nil))[:o.keyHashWidth] + "/" + key
+}
+
+// stripKey returns the path of a key in the bucket as seen by callers of the service.
+func (o *options) stripKey(key string) string {
+	key = strings.TrimPrefix(key, o.keyPrefix)
+	if o.keyHash != nil && len(key) > o.keyHashWidth && key[o.keyHashWidth] == '/' {
+		key = key[o.keyHashWidth+1:]
+	}
+	return key
+}
+
+// listPrefixes returns the prefixes to list for the objects under prefix, one per hash prefix
+// with WithKeyHashing.
+func (s *service) listPrefixes(prefix string) []string {
+	prefix = s.normalizeKey(prefix)
+	if s.opts.keyHash == nil {
+		return []string{s.opts.keyPrefix + prefix}
+	}
+	prefixes := make([]string, 1<<(4*uint(s.opts.keyHashWidth)))
+	for i := range prefixes {
+		prefixes[i] = fmt.Sprintf("%s%0*x/%s", s.opts.keyPrefix, s.opts.keyHashWidth, i, prefix)
+	}
+	return prefixes
+}
+
+// rulePrefix returns the key prefix of a lifecycle rule for the objects under prefix. With
+// WithKeyHashing only rules for all objects are possible.
+func (s *service) rulePrefix(prefix string) (string, error) {
+	if s.opts.keyHash == nil {
+		return s.key(prefix), nil
+	}
+	if prefix != "" {
+		return "", errKeyHashing
+	}
+	return s.opts.keyPrefix, nil
+}
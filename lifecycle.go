@@ -0,0 +1,224 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LifecycleFilter selects the objects a lifecycle rule applies to. Objects have to match the
+// prefix and all of the tags.
+type LifecycleFilter struct {
+	Prefix string
+	Tags   map[string]string
+}
+
+type lifecycleRule struct {
+	XMLName                        xml.Name                        `xml:"Rule"`
+	ID                             string                          `xml:"ID"`
+	Prefix                         *string                         `xml:"Prefix"`
+	Filter                         *lifecycleRuleFilter            `xml:"Filter"`
+	Status                         string                          `xml:"Status"`
+	Expiration                     *lifecycleExpiration            `xml:"Expiration"`
+	AbortIncompleteMultipartUpload *lifecycleAbortIncompleteUpload `xml:"AbortIncompleteMultipartUpload"`
+}
+
+type lifecycleRuleFilter struct {
+	Prefix string        `xml:"Prefix,omitempty"`
+	Tag    *lifecycleTag `xml:"Tag"`
+	And    *lifecycleAnd `xml:"And"`
+}
+
+type lifecycleAnd struct {
+	Prefix string         `xml:"Prefix,omitempty"`
+	Tags   []lifecycleTag `xml:"Tag"`
+}
+
+type lifecycleTag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type lifecycleExpiration struct {
+	Days int `xml:"Days"`
+}
+
+type lifecycleAbortIncompleteUpload struct {
+	DaysAfterInitiation int `xml:"DaysAfterInitiation"`
+}
+
+// storedLifecycleRule keeps a rule of the bucket's configuration verbatim, so rules this
+// package doesn't know about survive adding a rule.
+type storedLifecycleRule struct {
+	ID    string `xml:"ID"`
+	Inner string `xml:",innerxml"`
+}
+
+type storedLifecycleConfiguration struct {
+	Rules []storedLifecycleRule `xml:"Rule"`
+}
+
+func newLifecycleRuleFilter(filter LifecycleFilter) *lifecycleRuleFilter {
+	tags := make([]lifecycleTag, 0, len(filter.Tags))
+	for key, value := range filter.Tags {
+		tags = append(tags, lifecycleTag{Key: key, Value: value})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key })
+	switch {
+	case len(tags) == 0:
+		return &lifecycleRuleFilter{Prefix: filter.Prefix}
+	case len(tags) == 1 && filter.Prefix == "":
+		return &lifecycleRuleFilter{Tag: &tags[0]}
+	default:
+		return &lifecycleRuleFilter{And: &lifecycleAnd{Prefix: filter.Prefix, Tags: tags}}
+	}
+}
+
+func (s *service) AddLifeCycleRuleWithFilter(ruleId string, filter LifecycleFilter, daysToExpiry int) error {
+	prefix, err := s.rulePrefix(filter.Prefix)
+	if err != nil {
+		return err
+	}
+	filter.Prefix = prefix
+	return s.putLifecycleRule(lifecycleRule{
+		ID:         ruleId,
+		Filter:     newLifecycleRuleFilter(filter),
+		Status:     "Enabled",
+		Expiration: &lifecycleExpiration{Days: daysToExpiry},
+	})
+}
+
+// AddAbortIncompleteUploadRule aborts multipart uploads under prefix that weren't completed
+// within daysAfterInitiation days, removing their parts.
+func (s *service) AddAbortIncompleteUploadRule(ruleId, prefix string, daysAfterInitiation int) error {
+	prefix, err := s.rulePrefix(prefix)
+	if err != nil {
+		return err
+	}
+	return s.putLifecycleRule(lifecycleRule{
+		ID:     ruleId,
+		Filter: newLifecycleRuleFilter(LifecycleFilter{Prefix: prefix}),
+		Status: "Enabled",
+		AbortIncompleteMultipartUpload: &lifecycleAbortIncompleteUpload{
+			DaysAfterInitiation: daysAfterInitiation,
+		},
+	})
+}
+
+// maxLifecycleRuleIDLength is the longest ID S3 accepts for a lifecycle rule.
+const maxLifecycleRuleIDLength = 255
+
+// LifecycleRuleError is returned when a lifecycle rule is invalid, Field names the bad input.
+type LifecycleRuleError struct {
+	RuleID string
+	Field  string
+	Reason string
+}
+
+func (e *LifecycleRuleError) Error() string {
+	return fmt.Sprintf("invalid lifecycle rule %q: %s %s", e.RuleID, e.Field, e.Reason)
+}
+
+// validXMLText reports whether s can be represented in XML; encoding/xml would silently
+// replace the characters that can't.
+func validXMLText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			return false
+		}
+	}
+	return true
+}
+
+// validate checks rule before it's sent. Special characters such as & and < are fine, the rule
+// is encoded with encoding/xml.
+func (rule lifecycleRule) validate() error {
+	invalid := func(field, reason string) error {
+		return &LifecycleRuleError{RuleID: rule.ID, Field: field, Reason: reason}
+	}
+	if strings.TrimSpace(rule.ID) == "" {
+		return invalid("ID", "must not be empty")
+	}
+	if len(rule.ID) > maxLifecycleRuleIDLength {
+		return invalid("ID", fmt.Sprintf("must be at most %d bytes long", maxLifecycleRuleIDLength))
+	}
+	texts := map[string]string{"ID": rule.ID}
+	if rule.Prefix != nil {
+		texts["prefix"] = *rule.Prefix
+	}
+	if filter := rule.Filter; filter != nil {
+		texts["prefix"] = filter.Prefix
+		tags := []lifecycleTag{}
+		if filter.Tag != nil {
+			tags = append(tags, *filter.Tag)
+		}
+		if filter.And != nil {
+			texts["prefix"] = filter.And.Prefix
+			tags = append(tags, filter.And.Tags...)
+		}
+		for _, tag := range tags {
+			if tag.Key == "" {
+				return invalid("tag key", "must not be empty")
+			}
+			texts["tag "+tag.Key] = tag.Key + tag.Value
+		}
+	}
+	for field, text := range texts {
+		if !validXMLText(text) {
+			return invalid(field, "contains characters that can't be encoded in XML")
+		}
+	}
+	if rule.Expiration != nil && rule.Expiration.Days < 1 {
+		return invalid("days to expiry", fmt.Sprintf("must be at least 1, got %d", rule.Expiration.Days))
+	}
+	if rule.AbortIncompleteMultipartUpload != nil && rule.AbortIncompleteMultipartUpload.DaysAfterInitiation < 1 {
+		return invalid("days after initiation", fmt.Sprintf("must be at least 1, got %d", rule.AbortIncompleteMultipartUpload.DaysAfterInitiation))
+	}
+	return nil
+}
+
+// putLifecycleRule adds rule to the bucket's lifecycle configuration, replacing an existing
+// rule with the same ID.
+func (s *service) putLifecycleRule(rule lifecycleRule) error {
+	if err := rule.validate(); err != nil {
+		return err
+	}
+	ruleXML, err := xml.Marshal(rule)
+	if err != nil {
+		return err
+	}
+	defer s.resetBucketStatus()
+	start := time.Now()
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		current, err := s.getBucketLifecycle(ctx)
+		if err != nil {
+			return err
+		}
+		config := storedLifecycleConfiguration{}
+		if strings.TrimSpace(current) != "" {
+			if err := xml.Unmarshal([]byte(current), &config); err != nil {
+				return err
+			}
+		}
+		buf := bytes.NewBufferString("<LifecycleConfiguration>")
+		for _, stored := range config.Rules {
+			if stored.ID == rule.ID {
+				continue
+			}
+			buf.WriteString("<Rule>" + stored.Inner + "</Rule>")
+		}
+		buf.Write(ruleXML)
+		buf.WriteString("</LifecycleConfiguration>")
+		return s.s3Client.SetBucketLifecycleWithContext(ctx, s.bucketName, buf.String())
+	})
+	s.emit(Event{Op: EventLifecycle, Key: rule.ID, Err: err}, start)
+	return err
+}
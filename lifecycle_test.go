@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+)
+
+func TestAddLifeCycleRuleEscapesXML(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	if err := service.AddLifeCycleRule("tmp & <scratch>", "reports & <drafts>", 7); err != nil {
+		t.Fatal(err)
+	}
+	// adding a second rule has to keep the escaped first one intact
+	if err := service.AddLifeCycleRule("logs", "a&b<c>", 30); err != nil {
+		t.Fatal(err)
+	}
+	fake.mu.Lock()
+	stored := fake.lifecycle
+	fake.mu.Unlock()
+	config := struct {
+		Rules []lifecycleRule `xml:"Rule"`
+	}{}
+	if err := xml.Unmarshal(stored, &config); err != nil {
+		t.Fatalf("stored lifecycle configuration isn't valid XML: %v\n%s", err, stored)
+	}
+	want := map[string]string{"tmp & <scratch>": "reports & <drafts>/", "logs": "a&b<c>/"}
+	if len(config.Rules) != len(want) {
+		t.Fatalf("stored %d rules, want %d:\n%s", len(config.Rules), len(want), stored)
+	}
+	for _, rule := range config.Rules {
+		if rule.Prefix == nil || *rule.Prefix != want[rule.ID] {
+			t.Errorf("rule %q has the prefix %v, want %q", rule.ID, rule.Prefix, want[rule.ID])
+		}
+	}
+}
+
+func TestAddLifeCycleRuleValidation(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	tests := []struct {
+		id     string
+		prefix string
+		days   int
+		field  string
+	}{
+		{"", "tmp", 1, "ID"},
+		{"tmp", "tmp", 0, "days to expiry"},
+		{"tmp", "tmp", -1, "days to expiry"},
+		{"tmp", "tmp\x00", 1, "prefix"},
+	}
+	for _, test := range tests {
+		err := service.AddLifeCycleRule(test.id, test.prefix, test.days)
+		ruleErr := &LifecycleRuleError{}
+		if !errors.As(err, &ruleErr) || ruleErr.Field != test.field {
+			t.Errorf("AddLifeCycleRule(%q, %q, %d) = %v, want an error for %s", test.id, test.prefix, test.days, err, test.field)
+		}
+	}
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if fake.lifecycle != nil {
+		t.Error("an invalid rule was stored")
+	}
+}
@@ -0,0 +1,297 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// maxListPageSize is the most keys S3 returns per list request.
+const maxListPageSize = 1000
+
+// WithListPageSize sets how many keys are requested per page when listing objects, between 1
+// and 1000. Larger pages need fewer requests for big prefixes, smaller pages less memory. By
+// default the server decides, which is 1000 keys for GOBS.
+func WithListPageSize(n int) Option {
+	return func(o *options) {
+		o.listPageSize = n
+	}
+}
+
+func validListPageSize(n int) error {
+	if n < 0 || n > maxListPageSize {
+		return fmt.Errorf("s3 list page size must be between 1 and %d, got %d", maxListPageSize, n)
+	}
+	return nil
+}
+
+// listObjects lists all objects under prefix recursively until doneCh is closed. An error is
+// delivered as the last object. The keys are stripped of the key prefix.
+func (s *service) listObjects(prefix string, doneCh <-chan struct{}) <-chan ObjectInfo {
+	prefixes := s.listPrefixes(prefix)
+	if len(prefixes) == 1 && s.opts.keyPrefix == "" {
+		return s.listKeys(prefixes[0], doneCh)
+	}
+	objectCh := make(chan ObjectInfo, 1)
+	go func() {
+		defer close(objectCh)
+		for _, prefix := range prefixes {
+			for obj := range s.listKeys(prefix, doneCh) {
+				obj.Key = s.stripKey(obj.Key)
+				select {
+				case objectCh <- obj:
+				case <-doneCh:
+					return
+				}
+				if obj.Err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return objectCh
+}
+
+// listKeys lists all keys in the bucket under prefix.
+func (s *service) listKeys(prefix string, doneCh <-chan struct{}) <-chan ObjectInfo {
+	if s.opts.listPageSize == 0 {
+		return s.s3Client.ListObjectsV2(s.bucketName, prefix, true, doneCh)
+	}
+	objectCh := make(chan ObjectInfo, 1)
+	go func() {
+		defer close(objectCh)
+		token := ""
+		for {
+			var result minio.ListBucketV2Result
+			err := s.retry(context.Background(), func(ctx context.Context) error {
+				var err error
+				result, err = s.listObjectsV2(ctx, prefix, token, "")
+				return err
+			})
+			if err != nil {
+				select {
+				case objectCh <- ObjectInfo{Err: err}:
+				case <-doneCh:
+				}
+				return
+			}
+			for _, obj := range result.Contents {
+				// unlike ListObjectsV2 of the client, the raw listing keeps the quotes
+				obj.ETag = strings.Trim(obj.ETag, "\"")
+				select {
+				case objectCh <- obj:
+				case <-doneCh:
+					return
+				}
+			}
+			if !result.IsTruncated {
+				return
+			}
+			token = result.NextContinuationToken
+		}
+	}()
+	return objectCh
+}
+
+// ListObjects lists all objects under prefix with the Key, ETag, Size, LastModified and
+// StorageClass from the listing, so they can be compared with local files without a Stat per
+// object. The ETag of objects uploaded in parts isn't their MD5 (it contains a "-"), compare
+// the size of those instead.
+func (s *service) ListObjects(prefix string) ([]ObjectInfo, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	objects := []ObjectInfo{}
+	for obj := range s.listObjects(prefix, doneCh) {
+		if obj.Err != nil {
+			return nil, s.wrapError(obj.Err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// ForEachObject calls fn for every object under prefix, one page of the listing at a time, so
+// huge prefixes can be processed with little memory. Without recursive only the immediate
+// objects are listed, subfolders are passed to fn as ObjectInfo with just the Key (ending in
+// "/"). An error returned by fn stops the listing and is returned. ctx is checked between
+// pages.
+func (s *service) ForEachObject(ctx context.Context, prefix string, recursive bool, fn func(ObjectInfo) error) error {
+	delimiter := ""
+	if !recursive {
+		delimiter = "/"
+	}
+	for _, prefix := range s.listPrefixes(prefix) {
+		if err := s.forEachPage(ctx, prefix, delimiter, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachPage is ForEachObject for a prefix in the bucket.
+func (s *service) forEachPage(ctx context.Context, prefix, delimiter string, fn func(ObjectInfo) error) error {
+	token := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var result minio.ListBucketV2Result
+		err := s.retry(ctx, func(ctx context.Context) error {
+			var err error
+			result, err = s.listObjectsV2(ctx, prefix, token, delimiter)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, obj := range result.Contents {
+			obj.Key = s.stripKey(obj.Key)
+			obj.ETag = strings.Trim(obj.ETag, "\"")
+			if err := fn(obj); err != nil {
+				return err
+			}
+		}
+		for _, common := range result.CommonPrefixes {
+			if err := fn(ObjectInfo{Key: s.stripKey(common.Prefix)}); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// ObjectIterator iterates over the objects of a listing one page at a time, see
+// IterateObjects. Like bufio.Scanner, Next advances to the next object and Err returns the error
+// that stopped the iteration, if any:
+//
+//	it := service.IterateObjects(ctx, "reports/")
+//	for it.Next() {
+//		fmt.Println(it.Object().Key)
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type ObjectIterator struct {
+	s        *service
+	ctx      context.Context
+	prefixes []string
+	token    string
+	page     []ObjectInfo
+	current  ObjectInfo
+	last     bool
+	err      error
+}
+
+// IterateObjects returns an iterator over all objects under prefix. Pages are requested as the
+// iteration needs them; ctx is checked before every page.
+func (s *service) IterateObjects(ctx context.Context, prefix string) *ObjectIterator {
+	return &ObjectIterator{s: s, ctx: ctx, prefixes: s.listPrefixes(prefix)}
+}
+
+// Next advances to the next object and reports whether there is one. It returns false at the
+// end of the listing and after an error.
+func (it *ObjectIterator) Next() bool {
+	for len(it.page) == 0 {
+		if it.err != nil || it.last {
+			return false
+		}
+		it.fetch()
+	}
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+// fetch requests the next page of the listing.
+func (it *ObjectIterator) fetch() {
+	if it.err = it.ctx.Err(); it.err != nil {
+		return
+	}
+	var result minio.ListBucketV2Result
+	it.err = it.s.retry(it.ctx, func(ctx context.Context) error {
+		var err error
+		result, err = it.s.listObjectsV2(ctx, it.prefixes[0], it.token, "")
+		return err
+	})
+	if it.err != nil {
+		it.err = it.s.wrapError(it.err)
+		return
+	}
+	for _, obj := range result.Contents {
+		obj.Key = it.s.stripKey(obj.Key)
+		obj.ETag = strings.Trim(obj.ETag, "\"")
+		it.page = append(it.page, obj)
+	}
+	it.token = result.NextContinuationToken
+	if !result.IsTruncated {
+		it.prefixes = it.prefixes[1:]
+		it.token = ""
+	}
+	it.last = len(it.prefixes) == 0
+}
+
+// Object returns the object Next advanced to.
+func (it *ObjectIterator) Object() ObjectInfo {
+	return it.current
+}
+
+// Err returns the error that ended the iteration, or nil if the listing was complete.
+func (it *ObjectIterator) Err() error {
+	return it.err
+}
+
+// DirListing is the content of a "folder": the prefixes of its subfolders and the objects
+// directly inside of it.
+type DirListing struct {
+	Prefixes []string
+	Objects  []ObjectInfo
+}
+
+// ListDir lists the immediate subfolders and objects under prefix, like a file browser would.
+// The prefix is treated as folder with or without a trailing slash, an empty prefix lists the
+// root of the bucket.
+func (s *service) ListDir(prefix string) (*DirListing, error) {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	listing := &DirListing{}
+	// with WithKeyHashing a subfolder shows up under many hash prefixes
+	seen := map[string]bool{}
+	for _, prefix := range s.listPrefixes(prefix) {
+		token := ""
+		for {
+			var result minio.ListBucketV2Result
+			err := s.retry(context.Background(), func(ctx context.Context) error {
+				var err error
+				result, err = s.listObjectsV2(ctx, prefix, token, "/")
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, common := range result.CommonPrefixes {
+				if folder := s.stripKey(common.Prefix); !seen[folder] {
+					seen[folder] = true
+					listing.Prefixes = append(listing.Prefixes, folder)
+				}
+			}
+			for _, obj := range result.Contents {
+				// skip the marker object of the folder itself
+				if obj.Key != prefix {
+					obj.Key = s.stripKey(obj.Key)
+					obj.ETag = strings.Trim(obj.ETag, "\"")
+					listing.Objects = append(listing.Objects, obj)
+				}
+			}
+			if !result.IsTruncated {
+				break
+			}
+			token = result.NextContinuationToken
+		}
+	}
+	return listing, nil
+}
@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestEntry is one line of a manifest written by ExportManifest.
+type ManifestEntry struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+	StorageClass string    `json:"storage_class"`
+}
+
+// ExportManifest writes one JSON line per object under prefix to w while listing the bucket,
+// without holding the whole listing in memory.
+func (s *service) ExportManifest(prefix string, w io.Writer) error {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	encoder := json.NewEncoder(w)
+	for obj := range s.listObjects(prefix, doneCh) {
+		if obj.Err != nil {
+			return s.wrapError(obj.Err)
+		}
+		err := encoder.Encode(ManifestEntry{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			LastModified: obj.LastModified,
+			StorageClass: obj.StorageClass,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadManifest downloads exactly the objects listed in the manifest file at manifestPath,
+// as written by ExportManifest, to the same relative paths under localRoot. Objects that no
+// longer exist are reported with ErrNotFound in the returned *BatchError.
+func (s *service) DownloadManifest(manifestPath, localRoot string) error {
+	file, err := os.Open(manifestPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	root, err := filepath.Abs(localRoot)
+	if err != nil {
+		return err
+	}
+	objects := make(chan ObjectInfo)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(objects)
+		decoder := json.NewDecoder(file)
+		for {
+			entry := ManifestEntry{}
+			err := decoder.Decode(&entry)
+			if err == io.EOF {
+				return
+			}
+			select {
+			case objects <- ObjectInfo{Key: entry.Key, Err: err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return s.runObjects("download", objects, DefaultConcurrency, func(obj ObjectInfo) error {
+		localPath, err := localFilePath(root, obj.Key)
+		if err != nil {
+			return err
+		}
+		err = s.DownloadFile(obj.Key, localPath)
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,387 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// preservedHeaders are the standard headers and object attributes kept when an object's
+// metadata is replaced. Without the storage class and encryption headers a copy would be
+// stored as STANDARD and unencrypted.
+var preservedHeaders = []string{
+	"Cache-Control",
+	"Content-Disposition",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"Expires",
+	"X-Amz-Server-Side-Encryption",
+	"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
+	"X-Amz-Server-Side-Encryption-Context",
+	"X-Amz-Storage-Class",
+	"X-Amz-Website-Redirect-Location",
+}
+
+// aclHeaders carry the ACL of an object, as reported by GetObjectACL.
+var aclHeaders = []string{
+	"X-Amz-Acl",
+	"X-Amz-Grant-Full-Control",
+	"X-Amz-Grant-Read",
+	"X-Amz-Grant-Read-Acp",
+	"X-Amz-Grant-Write",
+	"X-Amz-Grant-Write-Acp",
+}
+
+const userMetadataPrefix = "X-Amz-Meta-"
+
+func isPreservedHeader(key string) bool {
+	for _, header := range preservedHeaders {
+		if strings.EqualFold(key, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataKey returns the key under which a metadata entry is kept between storedMetadata and
+// replaceMetadata: user metadata without its X-Amz-Meta- prefix, unless the bare name would be
+// taken for a header.
+func metadataKey(key string) string {
+	if len(key) <= len(userMetadataPrefix) || !strings.EqualFold(key[:len(userMetadataPrefix)], userMetadataPrefix) {
+		return key
+	}
+	name := key[len(userMetadataPrefix):]
+	if isPreservedHeader(name) || strings.HasPrefix(strings.ToLower(name), "x-amz-") {
+		return key
+	}
+	return name
+}
+
+// storedMetadata returns the user metadata, preserved headers and ACL of an object in the form
+// expected by replaceMetadata.
+func storedMetadata(header http.Header) map[string]string {
+	metadata := map[string]string{}
+	for key := range header {
+		if strings.HasPrefix(strings.ToLower(key), "x-amz-meta-") {
+			metadata[metadataKey(key)] = header.Get(key)
+		}
+	}
+	for _, key := range preservedHeaders {
+		if value := header.Get(key); value != "" {
+			metadata[key] = value
+		}
+	}
+	for _, key := range aclHeaders {
+		var grants []string
+		for _, value := range header[key] {
+			// grantees that are groups are reported without an id and can't be sent back
+			if value != "" && value != "id=" {
+				grants = append(grants, value)
+			}
+		}
+		if len(grants) > 0 {
+			metadata[key] = strings.Join(grants, ", ")
+		}
+	}
+	return metadata
+}
+
+// mergeMetadata sets the entries of src in dst, replacing entries whose keys only differ in
+// case or in the X-Amz-Meta- prefix.
+func mergeMetadata(dst, src map[string]string) {
+	for key, value := range src {
+		key = metadataKey(key)
+		for existing := range dst {
+			if strings.EqualFold(existing, key) {
+				delete(dst, existing)
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// objectMetadata returns the object at path together with its stored metadata, including its
+// ACL, which a copy onto itself would otherwise reset.
+func (s *service) objectMetadata(path string) (ObjectInfo, map[string]string, error) {
+	var info *ObjectInfo
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		info, err = s.s3Client.GetObjectACLWithContext(ctx, s.bucketName, s.key(path))
+		return err
+	})
+	if err != nil {
+		return ObjectInfo{}, nil, s.wrapError(err)
+	}
+	return *info, storedMetadata(info.Metadata), nil
+}
+
+func (s *service) UpdateMetadata(path string, metadata map[string]string, contentType string) error {
+	_, merged, err := s.objectMetadata(path)
+	if err != nil {
+		return err
+	}
+	mergeMetadata(merged, metadata)
+	if contentType != "" {
+		mergeMetadata(merged, map[string]string{"Content-Type": contentType})
+	}
+	return s.replaceMetadata(path, merged)
+}
+
+// replaceMetadata copies the object onto itself, replacing all of its metadata. Keys other than
+// the preserved headers and X-Amz-* headers are sent as user metadata.
+func (s *service) replaceMetadata(path string, metadata map[string]string) error {
+	headers := metadataHeaders(metadata)
+	headers["X-Amz-Metadata-Directive"] = "REPLACE"
+	core := minio.Core{Client: s.s3Client}
+	start := time.Now()
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		_, err := core.CopyObjectWithContext(ctx, s.bucketName, s.key(path), s.bucketName, s.key(path), headers)
+		return err
+	})
+	s.emit(Event{Op: EventMetadata, Key: path, Err: err}, start)
+	return err
+}
+
+// metadataHeaders returns the request headers for metadata returned by storedMetadata.
+func metadataHeaders(metadata map[string]string) map[string]string {
+	headers := map[string]string{}
+	for key, value := range metadata {
+		if isPreservedHeader(key) || strings.HasPrefix(strings.ToLower(key), "x-amz-") {
+			headers[key] = value
+		} else {
+			headers[userMetadataPrefix+key] = value
+		}
+	}
+	return headers
+}
+
+// GetWebsiteRedirect returns the website redirect location of path, or "" if it has none, see
+// WithWebsiteRedirect.
+func (s *service) GetWebsiteRedirect(path string) (string, error) {
+	var info ObjectInfo
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		info, err = s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.Metadata.Get("X-Amz-Website-Redirect-Location"), nil
+}
+
+// MetadataDiff lists the differences between the metadata of two objects, see CompareMetadata.
+type MetadataDiff struct {
+	Fields []FieldDiff
+}
+
+// FieldDiff is a metadata field whose values A and B differ. A missing field has the value "".
+type FieldDiff struct {
+	Field string
+	A     string
+	B     string
+}
+
+// Equal reports whether the objects had the same metadata.
+func (d *MetadataDiff) Equal() bool {
+	return len(d.Fields) == 0
+}
+
+// CompareMetadata compares Content-Type, size, ETag, storage class and user metadata
+// (X-Amz-Meta-*) of the objects at pathA and pathB, e.g. to check that a copy kept them.
+func (s *service) CompareMetadata(pathA, pathB string) (*MetadataDiff, error) {
+	infos := make([]ObjectInfo, 2)
+	for i, path := range []string{pathA, pathB} {
+		err := s.retry(context.Background(), func(ctx context.Context) error {
+			var err error
+			infos[i], err = s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	a, b := metadataFields(infos[0]), metadataFields(infos[1])
+	fields := []string{}
+	for field := range a {
+		fields = append(fields, field)
+	}
+	for field := range b {
+		if _, ok := a[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	diff := &MetadataDiff{}
+	for _, field := range fields {
+		if a[field] != b[field] {
+			diff.Fields = append(diff.Fields, FieldDiff{Field: field, A: a[field], B: b[field]})
+		}
+	}
+	return diff, nil
+}
+
+// metadataFields returns the fields CompareMetadata compares.
+func metadataFields(info ObjectInfo) map[string]string {
+	storageClass := info.StorageClass
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+	fields := map[string]string{
+		"Content-Type":  info.ContentType,
+		"Size":          strconv.FormatInt(info.Size, 10),
+		"ETag":          info.ETag,
+		"Storage-Class": storageClass,
+	}
+	for key := range info.Metadata {
+		if strings.HasPrefix(strings.ToLower(key), "x-amz-meta-") {
+			fields[http.CanonicalHeaderKey(key)] = info.Metadata.Get(key)
+		}
+	}
+	return fields
+}
+
+// FixContentTypes sets the content type inferred from the key extension on all objects under
+// prefix whose stored content type differs, and returns how many objects were fixed. Objects
+// with unknown extensions are left alone.
+func (s *service) FixContentTypes(prefix string) (int, error) {
+	var fixed int64
+	err := s.runBatch("fix content type of", prefix, DefaultConcurrency, func(obj ObjectInfo) error {
+		contentType := mime.TypeByExtension(pathpkg.Ext(obj.Key))
+		if contentType == "" {
+			return nil
+		}
+		info, metadata, err := s.objectMetadata(obj.Key)
+		if err != nil {
+			return err
+		}
+		if sameMediaType(info.ContentType, contentType) {
+			return nil
+		}
+		metadata["Content-Type"] = contentType
+		if err := s.replaceMetadata(obj.Key, metadata); err != nil {
+			return err
+		}
+		atomic.AddInt64(&fixed, 1)
+		return nil
+	})
+	return int(fixed), err
+}
+
+// sameMediaType compares content types ignoring parameters such as the charset.
+func sameMediaType(a, b string) bool {
+	mediaA, _, errA := mime.ParseMediaType(a)
+	mediaB, _, errB := mime.ParseMediaType(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(a, b)
+	}
+	return mediaA == mediaB
+}
+
+// Touch sets the last modified time of the object to now by copying it onto itself with its
+// current metadata. This restarts the countdown of lifecycle rules expiring objects some days
+// after their last modification, keeping objects in use alive.
+func (s *service) Touch(path string) error {
+	_, metadata, err := s.objectMetadata(path)
+	if err != nil {
+		return err
+	}
+	return s.replaceMetadata(path, metadata)
+}
+
+// FileInfo describes an object, see StatFile.
+type FileInfo struct {
+	ObjectInfo
+	// ExpiresAt is when a lifecycle rule will delete the object, or zero if no rule applies.
+	ExpiresAt time.Time
+	// ExpirationRuleID is the ID of the lifecycle rule behind ExpiresAt.
+	ExpirationRuleID string
+}
+
+// StatFile returns the size, metadata and lifecycle expiration of the object at path, e.g. to
+// show how long a temporary object lives independent of the URLs handed out for it. Missing
+// objects fail with ErrNotFound.
+func (s *service) StatFile(path string) (*FileInfo, error) {
+	var header http.Header
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodHead, path, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		header = resp.Header
+		return resp.Body.Close()
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNotFound, path, err)
+		}
+		return nil, s.wrapError(err)
+	}
+	size, _ := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	modified, _ := http.ParseTime(header.Get("Last-Modified"))
+	info := &FileInfo{ObjectInfo: ObjectInfo{
+		Key:          path,
+		ETag:         strings.Trim(header.Get("ETag"), `"`),
+		Size:         size,
+		LastModified: modified,
+		ContentType:  header.Get("Content-Type"),
+		StorageClass: header.Get("X-Amz-Storage-Class"),
+		Metadata:     header,
+	}}
+	if expiration := header.Get("X-Amz-Expiration"); expiration != "" {
+		info.ExpiresAt, info.ExpirationRuleID, err = parseExpiration(expiration)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// parseExpiration parses an X-Amz-Expiration header of the form
+// expiry-date="Fri, 23 Dec 2012 00:00:00 GMT", rule-id="delete-temp".
+func parseExpiration(value string) (time.Time, string, error) {
+	fields := map[string]string{}
+	rest := value
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		if rest == "" {
+			break
+		}
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			return time.Time{}, "", fmt.Errorf("s3 invalid expiration header %q", value)
+		}
+		key := strings.TrimSpace(rest[:i])
+		rest = rest[i+1:]
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return time.Time{}, "", fmt.Errorf("s3 invalid expiration header %q", value)
+			}
+			fields[key] = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			fields[key] = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+	}
+	expires, err := http.ParseTime(fields["expiry-date"])
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("s3 invalid expiration header %q: %v", value, err)
+	}
+	return expires, fields["rule-id"], nil
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/minio/minio-go/v6"
+)
+
+var errMmapUnsupported = errors.New("s3 memory mapped downloads aren't supported on this platform")
+
+// MmapHandle is an object downloaded to a temporary file and mapped into memory read-only, see
+// DownloadFileMmap.
+type MmapHandle struct {
+	data []byte
+	file *os.File
+}
+
+// Bytes returns the data of the object. It must not be modified, or used after Close.
+func (h *MmapHandle) Bytes() []byte {
+	return h.data
+}
+
+// Close unmaps the data and deletes the temporary file.
+func (h *MmapHandle) Close() error {
+	var err error
+	if h.data != nil {
+		err = munmap(h.data)
+		h.data = nil
+	}
+	if h.file != nil {
+		h.file.Close()
+		if removeErr := os.Remove(h.file.Name()); err == nil {
+			err = removeErr
+		}
+		h.file = nil
+	}
+	return err
+}
+
+// DownloadFileMmap downloads path to a temporary file (in the directory of WithTempDir or the
+// default one) and maps it into memory, so objects larger than the heap can be read like with
+// DownloadFileBytes. The caller must call Close, which deletes the file; the file stays behind if
+// the process exits before. Memory mapping is supported on Linux, macOS and the BSDs, elsewhere
+// an error is returned.
+func (s *service) DownloadFileMmap(path string) (*MmapHandle, error) {
+	if !mmapSupported {
+		return nil, errMmapUnsupported
+	}
+	file, err := ioutil.TempFile(s.opts.tempDir, "gobs-*.mmap")
+	if err != nil {
+		return nil, err
+	}
+	handle := &MmapHandle{file: file}
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+		_, err = io.Copy(file, object)
+		return err
+	})
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		handle.Close()
+		return nil, err
+	}
+	if stat.Size() == 0 {
+		// empty files can't be mapped
+		return handle, nil
+	}
+	if int64(int(stat.Size())) != stat.Size() {
+		handle.Close()
+		return nil, fmt.Errorf("s3 object %s is too large to be mapped on this platform", path)
+	}
+	if handle.data, err = mmap(file, stat.Size()); err != nil {
+		handle.Close()
+		return nil, err
+	}
+	return handle, nil
+}
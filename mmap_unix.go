@@ -0,0 +1,20 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package s3
+
+import (
+	"os"
+	"syscall"
+)
+
+const mmapSupported = true
+
+func mmap(file *os.File, size int64) ([]byte, error) {
+	// MAP_SHARED so the kernel can drop pages and read them from the file again
+	return syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
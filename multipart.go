@@ -0,0 +1,150 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// maxPartNumber is the highest part number of a multipart upload.
+const maxPartNumber = 10000
+
+// encodeUploadID returns the upload ID handed out by InitiateUpload, which carries the path so
+// the other calls only need the ID.
+func encodeUploadID(path, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path)) + ":" + id
+}
+
+func decodeUploadID(uploadID string) (path, id string, err error) {
+	i := strings.Index(uploadID, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("s3 upload ID %q wasn't returned by InitiateUpload", uploadID)
+	}
+	rawPath, err := base64.RawURLEncoding.DecodeString(uploadID[:i])
+	if err != nil {
+		return "", "", fmt.Errorf("s3 upload ID %q wasn't returned by InitiateUpload", uploadID)
+	}
+	return string(rawPath), uploadID[i+1:], nil
+}
+
+// InitiateUpload starts a multipart upload to path whose parts can be uploaded over time, e.g.
+// across restarts of the process, with UploadPart and which is finished with CompleteUpload or
+// AbortUpload. The returned ID is opaque. Until then the parts are billed, see
+// AddAbortIncompleteUploadRule for removing forgotten uploads.
+func (s *service) InitiateUpload(path, contentType string) (string, error) {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if err := s.prepareUpload(path, &opts); err != nil {
+		return "", err
+	}
+	var id string
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		id, err = s.newMultipartUpload(ctx, s.bucketName, s.key(path), opts.Header())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return encodeUploadID(path, id), nil
+}
+
+// UploadPart uploads part partNumber (1 to 10000) of an upload, replacing an earlier upload of
+// the same part. All parts but the last must be at least 5 MiB. Data that can't be seeked is
+// buffered in memory to find out its size.
+func (s *service) UploadPart(uploadID string, partNumber int, data io.Reader) error {
+	path, id, err := decodeUploadID(uploadID)
+	if err != nil {
+		return err
+	}
+	if partNumber < 1 || partNumber > maxPartNumber {
+		return fmt.Errorf("s3 part number must be between 1 and %d, got %d", maxPartNumber, partNumber)
+	}
+	seeker, ok := data.(io.ReadSeeker)
+	if !ok {
+		buf, err := ioutil.ReadAll(data)
+		if err != nil {
+			return err
+		}
+		seeker = bytes.NewReader(buf)
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	core := minio.Core{Client: s.s3Client}
+	return s.retry(context.Background(), func(ctx context.Context) error {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := core.PutObjectPartWithContext(ctx, s.bucketName, s.key(path), id, partNumber, seeker, end-start, "", "", nil)
+		return err
+	})
+}
+
+// CompleteUpload assembles the uploaded parts into the object, in the order of their numbers.
+func (s *service) CompleteUpload(uploadID string) error {
+	path, id, err := decodeUploadID(uploadID)
+	if err != nil {
+		return err
+	}
+	defer s.invalidate(path)
+	core := minio.Core{Client: s.s3Client}
+	parts := []minio.CompletePart{}
+	var size int64
+	marker := 0
+	for {
+		var result minio.ListObjectPartsResult
+		err := s.retry(context.Background(), func(ctx context.Context) error {
+			var err error
+			result, err = s.listObjectParts(ctx, path, id, marker)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+			size += part.Size
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("s3 upload of %s has no parts", path)
+	}
+	start := time.Now()
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		_, err := core.CompleteMultipartUploadWithContext(ctx, s.bucketName, s.key(path), id, parts)
+		return err
+	})
+	if err != nil {
+		size = 0
+	}
+	s.emit(Event{Op: EventUpload, Key: path, Size: size, Err: err}, start)
+	return err
+}
+
+// AbortUpload cancels an upload and removes its parts.
+func (s *service) AbortUpload(uploadID string) error {
+	path, id, err := decodeUploadID(uploadID)
+	if err != nil {
+		return err
+	}
+	core := minio.Core{Client: s.s3Client}
+	return s.retry(context.Background(), func(ctx context.Context) error {
+		return core.AbortMultipartUploadWithContext(ctx, s.bucketName, s.key(path), id)
+	})
+}
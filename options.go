@@ -0,0 +1,519 @@
+package s3
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"hash"
+	"net"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// Option configures optional behaviour of the service returned by NewService.
+type Option func(*options)
+
+type options struct {
+	tlsConfig           *tls.Config
+	rootCAs             []*x509.Certificate
+	insecureSkipVerify  bool
+	logger              Logger
+	multipartThreshold  int64
+	partSize            uint64
+	maxAttempts         int
+	backoff             BackoffStrategy
+	maxRetryDuration    time.Duration
+	existenceCacheTTL   time.Duration
+	logErrors           bool
+	appName             string
+	appVersion          string
+	defaultACL          string
+	listPageSize        int
+	shutdownCtx         context.Context
+	shutdownGrace       time.Duration
+	contentDisposition  string
+	bufferPool          bool
+	defaultContentType  string
+	tempDir             string
+	uploadRetryBuffer   int64
+	virtualHosted       bool
+	dispositionPolicy   DispositionPolicy
+	defaultMetadata     map[string]string
+	allowedContentTypes []string
+	ipv6Only            bool
+	keyPrefix           string
+	eventHook           func(Event)
+	maxConnsPerHost     int
+	maxIdleConnsPerHost int
+	keyNormalizer       func(string) string
+	trashPrefix         string
+	plainHTTP           bool
+	keepFailedUploads   bool
+	defaultStorageClass string
+	clock               func() time.Time
+	downloadCacheSize   int64
+	transferCounter     *TransferCounter
+	backupKeyFormat     func(string, time.Time) string
+	skipBucketCheck     bool
+	keyHashWidth        int
+	keyHash             func() hash.Hash
+	spillToDisk         bool
+	spillThreshold      int64
+	spillDir            string
+	region              string
+}
+
+// minPartSize and maxPartSize are the part size limits of S3 multipart uploads.
+const (
+	minPartSize = 5 * 1024 * 1024
+	maxPartSize = 5 * 1024 * 1024 * 1024
+)
+
+func validPartSize(partSize uint64) error {
+	if partSize < minPartSize || partSize > maxPartSize {
+		return fmt.Errorf("s3 part size must be between %d and %d bytes, got %d", minPartSize, maxPartSize, partSize)
+	}
+	return nil
+}
+
+// Logger is the minimal logging interface used by the service. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
+
+// WithLogger sets the logger used for warnings and diagnostics. By default nothing is logged.
+func WithLogger(logger Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithUserAgent adds the application name and version to the User-Agent of all requests, so
+// the traffic can be attributed in the GOBS access logs. By default the package name is added.
+func WithUserAgent(appName, version string) Option {
+	return func(o *options) {
+		o.appName = appName
+		o.appVersion = version
+	}
+}
+
+// WithVirtualHostedStyle addresses the bucket as subdomain of the endpoint
+// (bucket.obs.eu-de.otc.t-systems.com/key) instead of in the path
+// (obs.eu-de.otc.t-systems.com/bucket/key).
+func WithVirtualHostedStyle() Option {
+	return func(o *options) {
+		o.virtualHosted = true
+	}
+}
+
+// WithIPv6Only makes connections to the endpoint use IPv6 only. By default both IPv4 and IPv6
+// addresses of the endpoint are tried (RFC 6555), which also works on IPv6-only networks as
+// long as the endpoint has an AAAA record, but waits for IPv4 to time out first on networks
+// where IPv4 is black-holed.
+func WithIPv6Only() Option {
+	return func(o *options) {
+		o.ipv6Only = true
+	}
+}
+
+// WithKeyPrefix stores all objects of the service below prefix, e.g. "tenant-a/". Keys passed
+// to and returned by the service are relative to the prefix, which is added to and stripped
+// from every key, including listings, presigned URLs, upload forms and lifecycle rules. Keys in
+// other buckets, such as the destination of CopyToBucket, are used as they are.
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) {
+		o.keyPrefix = prefix
+	}
+}
+
+// WithKeyNormalizer passes every key given to the service through normalize before it's used,
+// e.g. strings.ToLower to avoid objects differing only in case. Listings return the normalized
+// keys. Keys are case-sensitive and used as they are by default. normalize must be idempotent, it
+// can be applied more than once to the same key. File names of browser uploads through
+// GenerateUploadForm can't be normalized.
+func WithKeyNormalizer(normalize func(key string) string) Option {
+	return func(o *options) {
+		o.keyNormalizer = normalize
+	}
+}
+
+// WithLowercaseKeys is WithKeyNormalizer(strings.ToLower).
+func WithLowercaseKeys() Option {
+	return WithKeyNormalizer(strings.ToLower)
+}
+
+// WithPlainHTTP connects to the endpoint over plain HTTP instead of HTTPS, e.g. for a local test
+// server. Presigned and public URLs use the same scheme. Alternatively the endpoint passed to
+// NewService can start with "http://".
+func WithPlainHTTP() Option {
+	return func(o *options) {
+		o.plainHTTP = true
+	}
+}
+
+// WithSkipBucketCheck makes NewService skip checking that the bucket exists, which needs the
+// s3:ListBucket permission that credentials restricted to objects may lack. A missing bucket or
+// invalid credentials then fail the first operation instead of NewService. Reconfigure with
+// verify still checks the bucket.
+func WithSkipBucketCheck() Option {
+	return func(o *options) {
+		o.skipBucketCheck = true
+	}
+}
+
+// WithRegion sets the region of the bucket, e.g. eu-de, instead of requesting it with
+// GetBucketLocation on first use, which credentials restricted to objects may not be allowed to.
+func WithRegion(region string) Option {
+	return func(o *options) {
+		o.region = region
+	}
+}
+
+// WithMaxConnsPerHost limits the number of connections to the endpoint, including ones in use.
+// Requests beyond the limit wait for a free connection. By default there is no limit.
+func WithMaxConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.maxConnsPerHost = n
+	}
+}
+
+// WithMaxIdleConnsPerHost sets how many idle connections to the endpoint are kept for reuse, 16
+// by default. It should be at least the concurrency of directory operations (DefaultConcurrency
+// unless set with WithConcurrency), otherwise parallel transfers keep opening new connections.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.maxIdleConnsPerHost = n
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for connections to the endpoint.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = config
+	}
+}
+
+// WithRootCAs trusts the given certificates in addition to the configured root CAs,
+// e.g. for gateways signed by a private CA.
+func WithRootCAs(certs ...*x509.Certificate) Option {
+	return func(o *options) {
+		o.rootCAs = append(o.rootCAs, certs...)
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the endpoint's TLS certificate.
+// It is meant for local development against self-signed endpoints only and must
+// never be used in production; a warning is logged when it is enabled.
+func WithInsecureSkipVerify() Option {
+	return func(o *options) {
+		o.insecureSkipVerify = true
+	}
+}
+
+// WithMultipartThreshold buffers up to threshold bytes of uploads with unknown size. Streams
+// ending within the threshold are uploaded with a single PUT, larger ones as multipart upload
+// using the configured part size. It is disabled by default.
+func WithMultipartThreshold(threshold int64) Option {
+	return func(o *options) {
+		o.multipartThreshold = threshold
+	}
+}
+
+// WithPartSize sets the part size of multipart uploads, which bounds the memory buffered per
+// upload of unknown size. It must be at least 5 MiB; by default minio picks the part size.
+func WithPartSize(partSize uint64) Option {
+	return func(o *options) {
+		o.partSize = partSize
+	}
+}
+
+func newTransport(o *options) (http.RoundTripper, error) {
+	rt, err := minio.DefaultTransport(true)
+	if err != nil {
+		return nil, err
+	}
+	tr, ok := rt.(*http.Transport)
+	if !ok {
+		return &retryAfterTransport{base: rt}, nil
+	}
+	if o.maxConnsPerHost > 0 {
+		tr.MaxConnsPerHost = o.maxConnsPerHost
+	}
+	if o.maxIdleConnsPerHost > 0 {
+		tr.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+		if tr.MaxIdleConns < o.maxIdleConnsPerHost {
+			tr.MaxIdleConns = o.maxIdleConnsPerHost
+		}
+	}
+	if o.ipv6Only {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp6", addr)
+		}
+	}
+	if o.tlsConfig != nil {
+		config := o.tlsConfig.Clone()
+		// keep the http/2 protocols negotiated by the default transport
+		if len(config.NextProtos) == 0 {
+			config.NextProtos = tr.TLSClientConfig.NextProtos
+		}
+		if config.MinVersion == 0 {
+			config.MinVersion = tr.TLSClientConfig.MinVersion
+		}
+		if config.RootCAs == nil {
+			config.RootCAs = tr.TLSClientConfig.RootCAs
+		}
+		tr.TLSClientConfig = config
+	}
+	if len(o.rootCAs) > 0 {
+		// the pool of WithTLSConfig is shared with the caller and must not be modified
+		pool, err := copyCertPool(tr.TLSClientConfig.RootCAs)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range o.rootCAs {
+			pool.AddCert(cert)
+		}
+		tr.TLSClientConfig.RootCAs = pool
+	}
+	if o.insecureSkipVerify {
+		o.logger.Printf("s3: TLS certificate verification is disabled, do not use this in production")
+		tr.TLSClientConfig.InsecureSkipVerify = true
+	}
+	return &retryAfterTransport{base: tr}, nil
+}
+
+// DownloadOption configures a single download.
+type DownloadOption func(*downloadOptions)
+
+type downloadOptions struct {
+	preserveModTime   bool
+	verifyChecksum    bool
+	unmodifiedSince   time.Time
+	ifMatch           string
+	encryptionContext map[string]string
+}
+
+// WithVerifyChecksum compares the MD5 of the downloaded file with the object's ETag and
+// removes the file on a mismatch. Multipart objects and objects encrypted with SSE-KMS or SSE-C
+// have no MD5 ETag, for them only the size is compared.
+func WithVerifyChecksum() DownloadOption {
+	return func(o *downloadOptions) {
+		o.verifyChecksum = true
+	}
+}
+
+// WithPreserveModTime sets the modification time of the downloaded file to the last modified
+// time of the object instead of the time of the download.
+func WithPreserveModTime() DownloadOption {
+	return func(o *downloadOptions) {
+		o.preserveModTime = true
+	}
+}
+
+// WithIfUnmodifiedSince downloads the object only if it wasn't modified after t, otherwise the
+// download fails with a *PreconditionFailedError, e.g. to read a consistent snapshot.
+func WithIfUnmodifiedSince(t time.Time) DownloadOption {
+	return func(o *downloadOptions) {
+		o.unmodifiedSince = t
+	}
+}
+
+// WithIfMatch downloads the object only if its ETag is etag, otherwise the download fails with
+// a *PreconditionFailedError.
+func WithIfMatch(etag string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.ifMatch = strings.Trim(etag, "\"")
+	}
+}
+
+// WithEncryptionContext downloads the object only if it was encrypted with SSE-KMS and exactly
+// context, see WithKMSEncryption, otherwise the download fails with
+// ErrEncryptionContextMismatch. S3 doesn't take the context on reads, KMS checks it against its
+// key policy by itself, so this guards against objects uploaded without the required context.
+func WithEncryptionContext(context map[string]string) DownloadOption {
+	return func(o *downloadOptions) {
+		o.encryptionContext = context
+	}
+}
+
+// condition describes the preconditions of the download for a *PreconditionFailedError.
+func (o *downloadOptions) condition() string {
+	conditions := []string{}
+	if !o.unmodifiedSince.IsZero() {
+		conditions = append(conditions, "modified after "+o.unmodifiedSince.Format(time.RFC3339))
+	}
+	if o.ifMatch != "" {
+		conditions = append(conditions, fmt.Sprintf("ETag isn't %q", o.ifMatch))
+	}
+	if len(conditions) == 0 {
+		return "changed during the download"
+	}
+	return strings.Join(conditions, " or ")
+}
+
+// DirectoryOption configures a single directory operation such as DownloadDirectory.
+type DirectoryOption func(*directoryOptions)
+
+type directoryOptions struct {
+	filter      func(ObjectInfo) bool
+	glob        string
+	flatten     bool
+	collision   CollisionPolicy
+	concurrency int
+	download    []DownloadOption
+	mapper      func(key string) string
+	failFast    bool
+}
+
+// WithFailFast stops a directory operation at the first failed object: no further transfers
+// are started and running ones are cancelled. By default all objects are attempted and the
+// failures are reported together.
+func WithFailFast() DirectoryOption {
+	return func(o *directoryOptions) {
+		o.failFast = true
+	}
+}
+
+// WithKeyMapper transforms the path of every object relative to the source directory into its
+// path relative to the destination directory, e.g. to strip or add a folder. Objects for which
+// mapper returns an empty string are skipped.
+func WithKeyMapper(mapper func(key string) string) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.mapper = mapper
+	}
+}
+
+func (o *directoryOptions) mapKey(key string) string {
+	if o.mapper == nil {
+		return key
+	}
+	return o.mapper(key)
+}
+
+// DefaultConcurrency is the number of objects transferred in parallel by directory operations.
+const DefaultConcurrency = 8
+
+// WithConcurrency sets how many objects are transferred in parallel, DefaultConcurrency if not
+// set. A value of 1 transfers the objects one after another.
+func WithConcurrency(n int) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithDownloadOptions applies opts to every file downloaded by DownloadDirectory.
+func WithDownloadOptions(opts ...DownloadOption) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.download = append(o.download, opts...)
+	}
+}
+
+func newDirectoryOptions(opts []DirectoryOption) directoryOptions {
+	o := directoryOptions{concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency < 1 {
+		o.concurrency = 1
+	}
+	return o
+}
+
+// CollisionPolicy decides what happens when flattening maps two objects to the same file name.
+type CollisionPolicy int
+
+const (
+	// CollisionError fails the colliding object and keeps the first one.
+	CollisionError CollisionPolicy = iota
+	// CollisionSuffix appends a counter to the file name, e.g. report_1.pdf.
+	CollisionSuffix
+)
+
+// WithFlatten writes every object directly into the destination directory using only the base
+// name of its key, discarding the nested structure.
+func WithFlatten(collision CollisionPolicy) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.flatten = true
+		o.collision = collision
+	}
+}
+
+// WithFilter only includes objects for which filter returns true.
+func WithFilter(filter func(ObjectInfo) bool) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.filter = filter
+	}
+}
+
+// WithGlob only includes objects matching pattern (see path.Match). The pattern is matched
+// against the key relative to the directory, or against the base name if it contains no slash.
+func WithGlob(pattern string) DirectoryOption {
+	return func(o *directoryOptions) {
+		o.glob = pattern
+	}
+}
+
+func (o *directoryOptions) matches(obj ObjectInfo, dir string) bool {
+	if o.glob != "" && !matchGlob(o.glob, relativeKey(obj.Key, dir)) {
+		return false
+	}
+	if o.filter != nil && !o.filter(obj) {
+		return false
+	}
+	return true
+}
+
+func matchGlob(pattern, key string) bool {
+	if !strings.Contains(pattern, "/") {
+		key = path.Base(key)
+	}
+	ok, _ := path.Match(pattern, key)
+	return ok
+}
+
+func relativeKey(key, dir string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(key, dir), "/")
+}
+
+// localFilePath returns the path of the file name, relative with slashes, below the absolute
+// directory root. Names of untrusted keys like "../x" which point outside of root fail.
+func localFilePath(root, name string) (string, error) {
+	localPath := filepath.Join(root, filepath.FromSlash(name))
+	if !strings.HasPrefix(localPath, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s points outside of %s", name, root)
+	}
+	return localPath, nil
+}
+
+// localName returns the file name relative to the local directory for obj. used tracks the
+// names handed out so far, it is only consulted when flattening.
+func (o *directoryOptions) localName(obj ObjectInfo, dir string, used map[string]bool) (string, error) {
+	name := o.mapKey(relativeKey(obj.Key, dir))
+	if !o.flatten || name == "" {
+		return name, nil
+	}
+	name = path.Base(name)
+	if used[name] {
+		if o.collision != CollisionSuffix {
+			return "", fmt.Errorf("file name %s is already used by another object", name)
+		}
+		ext := path.Ext(name)
+		base := strings.TrimSuffix(name, ext)
+		for i := 1; used[name]; i++ {
+			name = fmt.Sprintf("%s_%d%s", base, i, ext)
+		}
+	}
+	used[name] = true
+	return name, nil
+}
@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// maxPooledBufferSize keeps the pool from holding on to the memory of exceptionally large objects.
+const maxPooledBufferSize = 64 * 1024 * 1024
+
+// WithBufferPool makes DownloadFileBuffer reuse the buffers released by callers, reducing
+// allocations of services reading many objects into memory.
+func WithBufferPool() Option {
+	return func(o *options) {
+		o.bufferPool = true
+	}
+}
+
+// Buffer holds an object read by DownloadFileBuffer. The buffer is owned by the caller until
+// Release is called; afterwards neither the Buffer nor any slice returned by Bytes may be used,
+// as the memory is handed to the next download.
+type Buffer struct {
+	buf  *bytes.Buffer
+	pool *sync.Pool
+}
+
+// Bytes returns the content of the object, valid until Release.
+func (b *Buffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Len returns the size of the object.
+func (b *Buffer) Len() int {
+	return b.buf.Len()
+}
+
+// Release returns the buffer to the pool. Calling it more than once has no effect.
+func (b *Buffer) Release() {
+	if b.buf == nil {
+		return
+	}
+	if b.pool != nil && b.buf.Cap() <= maxPooledBufferSize {
+		b.buf.Reset()
+		b.pool.Put(b.buf)
+	}
+	b.buf = nil
+}
+
+func newBufferPool(enabled bool) *sync.Pool {
+	if !enabled {
+		return nil
+	}
+	return &sync.Pool{New: func() interface{} { return &bytes.Buffer{} }}
+}
+
+// DownloadFileBuffer is DownloadFileBytes reading into a buffer from the service's pool if
+// WithBufferPool is set. The caller must call Release once it is done with the buffer.
+func (s *service) DownloadFileBuffer(path string) (*Buffer, error) {
+	buffer := &Buffer{buf: &bytes.Buffer{}, pool: s.buffers}
+	if s.buffers != nil {
+		buffer.buf = s.buffers.Get().(*bytes.Buffer)
+	}
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		buffer.buf.Reset()
+		object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), minio.GetObjectOptions{})
+		if err != nil {
+			return err
+		}
+		defer object.Close()
+		info, err := object.Stat()
+		if err != nil {
+			return err
+		}
+		buffer.buf.Grow(int(info.Size))
+		_, err = io.Copy(buffer.buf, object)
+		return err
+	})
+	if err != nil {
+		buffer.Release()
+		if isNotFound(err) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrNotFound, path, err)
+		}
+		return nil, err
+	}
+	return buffer, nil
+}
@@ -0,0 +1,331 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+)
+
+const (
+	dispositionParam = "response-content-disposition"
+	contentTypeParam = "response-content-type"
+)
+
+// WithDefaultContentDisposition sets the Content-Disposition presigned URLs are served with,
+// e.g. "attachment" to make browsers download the files. It defaults to "inline".
+func WithDefaultContentDisposition(disposition string) Option {
+	return func(o *options) {
+		o.contentDisposition = disposition
+	}
+}
+
+// PresignOption configures a single presigned URL.
+type PresignOption func(*presignOptions)
+
+type presignOptions struct {
+	query            url.Values
+	unsigned         url.Values
+	disposition      string
+	statContentType  bool
+	inferContentType bool
+	conditions       []string
+}
+
+// DispositionPolicy maps media types to the Content-Disposition presigned URLs are served with.
+// Keys are media types like "application/pdf" or wildcards like "image/*", the value of "*" is
+// used for all other types.
+type DispositionPolicy map[string]string
+
+// DefaultDispositionPolicy displays images and PDFs in the browser and downloads everything else.
+var DefaultDispositionPolicy = DispositionPolicy{
+	"image/*":         "inline",
+	"application/pdf": "inline",
+	"*":               "attachment",
+}
+
+// disposition returns the disposition for contentType, or "" if the policy has none.
+func (p DispositionPolicy) disposition(contentType string) string {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		if disposition, ok := p[media]; ok {
+			return disposition
+		}
+		if i := strings.Index(media, "/"); i >= 0 {
+			if disposition, ok := p[media[:i]+"/*"]; ok {
+				return disposition
+			}
+		}
+	}
+	return p["*"]
+}
+
+// WithDispositionPolicy picks the Content-Disposition of presigned URLs by the content type of
+// the object, which costs a HEAD request per URL. A disposition passed with
+// WithContentDisposition still takes precedence.
+func WithDispositionPolicy(policy DispositionPolicy) Option {
+	return func(o *options) {
+		o.dispositionPolicy = policy
+	}
+}
+
+// WithStoredContentType serves the URL with the content type stored on the object, or the one
+// inferred from the key extension if the stored type is generic, so browsers render the file.
+// It costs a HEAD request; use WithInferredContentType to skip it.
+func WithStoredContentType() PresignOption {
+	return func(o *presignOptions) {
+		o.statContentType = true
+		o.inferContentType = true
+	}
+}
+
+// WithInferredContentType serves the URL with the content type inferred from the key extension.
+func WithInferredContentType() PresignOption {
+	return func(o *presignOptions) {
+		o.inferContentType = true
+	}
+}
+
+// genericContentType reports whether contentType says nothing about how to render the object.
+func genericContentType(contentType string) bool {
+	media, _, err := mime.ParseMediaType(contentType)
+	return err != nil || media == "application/octet-stream" || media == "binary/octet-stream"
+}
+
+// setResponseHeaders sets the content type and disposition parameters requested by o, looking
+// up the stored content type if needed.
+func (s *service) setResponseHeaders(path string, o *presignOptions) error {
+	usePolicy := o.disposition == "" && s.opts.dispositionPolicy != nil
+	contentType := ""
+	if o.statContentType || usePolicy {
+		info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+		if err != nil {
+			return s.wrapError(err)
+		}
+		if !genericContentType(info.ContentType) {
+			contentType = info.ContentType
+		}
+	}
+	if contentType == "" && (o.inferContentType || usePolicy) {
+		contentType = mime.TypeByExtension(pathpkg.Ext(path))
+	}
+	if contentType != "" && (o.statContentType || o.inferContentType) && o.query.Get(contentTypeParam) == "" {
+		o.query.Set(contentTypeParam, contentType)
+	}
+	switch {
+	case o.disposition != "":
+		o.query.Set(dispositionParam, o.disposition)
+	case usePolicy:
+		if disposition := s.opts.dispositionPolicy.disposition(contentType); disposition != "" {
+			o.query.Set(dispositionParam, disposition)
+		}
+	}
+	return nil
+}
+
+// WithContentDisposition overrides the service's default Content-Disposition for one URL, e.g.
+// `attachment; filename="report.pdf"`.
+func WithContentDisposition(disposition string) PresignOption {
+	return func(o *presignOptions) {
+		o.disposition = disposition
+	}
+}
+
+// WithQueryParam adds a query parameter to the URL, e.g. a version to bust caches. It is
+// part of the signature, so it can't be changed or removed without invalidating the URL.
+func WithQueryParam(key, value string) PresignOption {
+	return func(o *presignOptions) {
+		o.query.Add(key, value)
+	}
+}
+
+// WithUnsignedQueryParam appends a query parameter after signing the URL. GOBS rejects
+// requests with parameters that aren't signed, so this is only useful for parameters a CDN
+// or proxy strips before forwarding the request; use WithQueryParam otherwise.
+func WithUnsignedQueryParam(key, value string) PresignOption {
+	return func(o *presignOptions) {
+		o.unsigned.Add(key, value)
+	}
+}
+
+// ErrUnsupportedCondition is returned when a presigned URL is requested with a condition GOBS
+// can't enforce on it, see WithSourceIP and WithReferer.
+var ErrUnsupportedCondition = errors.New("s3 condition can't be enforced on presigned URLs")
+
+// WithSourceIP is meant to restrict a presigned URL to clients from cidr. GOBS only evaluates
+// source IP conditions in bucket policies (aws:SourceIp), not in the signature of a presigned
+// URL, so presigning with this option fails with ErrUnsupportedCondition instead of returning a
+// URL that works from everywhere. Restrict the prefix with a bucket policy instead.
+func WithSourceIP(cidr string) PresignOption {
+	return func(o *presignOptions) {
+		o.conditions = append(o.conditions, "source IP "+cidr)
+	}
+}
+
+// WithReferer is meant to restrict a presigned URL to requests from pages under referer. Like
+// WithSourceIP it fails with ErrUnsupportedCondition, referer conditions (aws:Referer) are only
+// evaluated in bucket policies. Browser uploads can be restricted with the conditions of
+// GenerateUploadForm.
+func WithReferer(referer string) PresignOption {
+	return func(o *presignOptions) {
+		o.conditions = append(o.conditions, "referer "+referer)
+	}
+}
+
+// presignGet returns a presigned GET URL for path with the query parameters of opts.
+func (s *service) presignGet(path string, expiration time.Duration, opts []PresignOption) (*url.URL, error) {
+	o := s.presignOptions(opts)
+	if len(o.conditions) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCondition, strings.Join(o.conditions, ", "))
+	}
+	for _, values := range []url.Values{o.query, o.unsigned} {
+		for key := range values {
+			if strings.HasPrefix(strings.ToLower(key), "x-amz-") {
+				return nil, fmt.Errorf("query parameter %s is reserved for the signature", key)
+			}
+		}
+	}
+	if err := s.setResponseHeaders(path, &o); err != nil {
+		return nil, err
+	}
+	u, err := s.s3Client.PresignedGetObject(s.bucketName, s.key(path), expiration, o.query)
+	if err != nil {
+		return nil, err
+	}
+	if s.opts.clock != nil {
+		if u, err = s.resign(u); err != nil {
+			return nil, err
+		}
+	}
+	if len(o.unsigned) > 0 {
+		// append instead of re-encoding, which would alter the encoding of the signed parameters
+		u.RawQuery += "&" + o.unsigned.Encode()
+	}
+	return u, nil
+}
+
+// GetFileUrls presigns GET URLs for paths using up to GOMAXPROCS goroutines. Once ctx is done
+// no further URLs are generated; the URLs generated so far are returned with ctx.Err().
+// Otherwise paths which failed are reported in a *BatchError next to the other URLs.
+func (s *service) GetFileUrls(ctx context.Context, paths []string, expiration time.Duration, opts ...PresignOption) (map[string]*url.URL, error) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	pathCh := make(chan string)
+	urls := map[string]*url.URL{}
+	errs := map[string]error{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range pathCh {
+				u, err := s.presignGet(path, expiration, opts)
+				mu.Lock()
+				if err != nil {
+					errs[path] = err
+				} else {
+					urls[path] = u
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, path := range paths {
+		select {
+		case pathCh <- path:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(pathCh)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return urls, err
+	}
+	return urls, newBatchError("presign", errs)
+}
+
+// Errors of VerifyURL, test for them with errors.Is.
+var (
+	ErrURLExpired   = errors.New("s3 presigned URL has expired")
+	ErrURLForbidden = errors.New("s3 presigned URL was rejected")
+)
+
+// amzDateFormat is the format of X-Amz-Date.
+const amzDateFormat = "20060102T150405Z"
+
+// VerifyURL checks that a presigned GET URL works by requesting its first byte; a HEAD request
+// wouldn't match the signature. URLs whose X-Amz-Date and X-Amz-Expires are in the past, by the
+// clock set with WithClock if any, fail with ErrURLExpired without a request. URLs GOBS
+// rejects fail with ErrURLExpired or ErrURLForbidden, e.g. on a signature mismatch caused by
+// clock skew. It is meant for diagnostics, every call sends a request.
+func (s *service) VerifyURL(u *url.URL) error {
+	query := u.Query()
+	if date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date")); err == nil {
+		if seconds, err := strconv.Atoi(query.Get("X-Amz-Expires")); err == nil {
+			if expiry := date.Add(time.Duration(seconds) * time.Second); s.now().After(expiry) {
+				return fmt.Errorf("%w: at %s", ErrURLExpired, expiry.Format(time.RFC3339))
+			}
+		}
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	resp, err := (&http.Client{Transport: s.transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// empty objects can't satisfy the range
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return nil
+	}
+	errResp := rawErrorResponse(resp, s.bucketName, "")
+	if resp.StatusCode != http.StatusForbidden {
+		return s.wrapError(errResp)
+	}
+	kind := ErrURLForbidden
+	if strings.Contains(strings.ToLower(errorResponse(errResp).Message), "expired") {
+		kind = ErrURLExpired
+	}
+	return fmt.Errorf("%w: %v", kind, s.wrapError(errResp))
+}
+
+// presignOptions returns the query parameters of a presigned URL, the service defaults
+// overridden by opts.
+func (s *service) presignOptions(opts []PresignOption) presignOptions {
+	o := presignOptions{query: make(url.Values, len(s.urlValues)), unsigned: url.Values{}}
+	for key, value := range s.urlValues {
+		o.query[key] = append([]string(nil), value...)
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// PublicURL returns the unsigned URL of the object, which is only accessible if the object or
+// bucket is public. It is built from the configuration without any request.
+func (s *service) PublicURL(path string) (*url.URL, error) {
+	if err := s3utils.CheckValidObjectName(path); err != nil {
+		return nil, err
+	}
+	return s.objectURL(s.bucketName, s.key(path)), nil
+}
@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var specialKeys = []string{
+	"dir/with space.txt",
+	"dir/a+b.txt",
+	"dir/a&b=c.txt",
+	"dir/ünïcödé/日本.txt",
+	"dir/mixed +&?#%.txt",
+}
+
+func TestGetFileUrlSpecialCharacters(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	for _, key := range specialKeys {
+		fake.put(key, []byte(key), nil)
+		u, err := service.GetFileUrl(key, time.Minute)
+		if err != nil {
+			t.Errorf("GetFileUrl(%q): %v", key, err)
+			continue
+		}
+		if got := fetch(t, u); got != key {
+			t.Errorf("GET %s returned %q, want %q", u, got, key)
+		}
+	}
+	// the fake endpoint has to reject URLs which don't match their signature
+	u, err := service.GetFileUrl(specialKeys[0], time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path, u.RawPath = u.Path+"x", ""
+	resp, err := http.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("GET of a tampered URL returned %s", resp.Status)
+	}
+}
+
+func TestPublicURLSpecialCharacters(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	for _, key := range specialKeys {
+		fake.put(key, []byte(key), nil)
+		u, err := service.PublicURL(key)
+		if err != nil {
+			t.Errorf("PublicURL(%q): %v", key, err)
+			continue
+		}
+		if want := "/" + fakeBucket + "/" + key; u.Path != want {
+			t.Errorf("PublicURL(%q) has the path %q, want %q", key, u.Path, want)
+		}
+		if got := fetch(t, u); got != key {
+			t.Errorf("GET %s returned %q, want %q", u, got, key)
+		}
+	}
+}
+
+// fetch returns the body of a GET of u as a string.
+func fetch(t *testing.T, u *url.URL) string {
+	t.Helper()
+	resp, err := http.Get(u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GET %s: %s %s", u, resp.Status, body)
+	}
+	return string(body)
+}
+
+func TestVerifyURLClock(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	fake.put("a.txt", []byte("a"), nil)
+	u, err := fake.newService().GetFileUrl("a.txt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.newService().VerifyURL(u); err != nil {
+		t.Errorf("VerifyURL: %v", err)
+	}
+	ahead := fake.newService(WithClockOffset(time.Hour))
+	requests := len(fake.requestLog())
+	if err := ahead.VerifyURL(u); !errors.Is(err, ErrURLExpired) {
+		t.Errorf("VerifyURL with a clock an hour ahead returned %v, want ErrURLExpired", err)
+	}
+	if got := len(fake.requestLog()); got != requests {
+		t.Errorf("VerifyURL of an expired URL sent %d requests", got-requests)
+	}
+}
@@ -0,0 +1,137 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/s3utils"
+	"github.com/minio/minio-go/v6/pkg/signer"
+)
+
+// doRaw sends a signed request for an API minio-go doesn't cover. An empty key addresses the
+// bucket. Responses with a status other than 2xx are returned as minio.ErrorResponse.
+func (s *service) doRaw(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	sum := sha256.Sum256(body)
+	return s.doRawStream(ctx, method, key, query, header, bytes.NewReader(body), int64(len(body)), hex.EncodeToString(sum[:]))
+}
+
+// doRawStream is doRaw sending size bytes read from body, whose SHA256 is payloadHash.
+func (s *service) doRawStream(ctx context.Context, method, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	if key != "" {
+		key = s.key(key)
+	}
+	return s.sendStream(ctx, s.bucketName, method, key, query, header, body, size, payloadHash)
+}
+
+// send is doRaw for key in bucket, both used as they are.
+func (s *service) send(ctx context.Context, bucket, method, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	sum := sha256.Sum256(body)
+	return s.sendStream(ctx, bucket, method, key, query, header, bytes.NewReader(body), int64(len(body)), hex.EncodeToString(sum[:]))
+}
+
+// sendStream is doRawStream for key in bucket, both used as they are.
+func (s *service) sendStream(ctx context.Context, bucket, method, key string, query url.Values, header http.Header, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	location, err := s.GetBucketRegion()
+	if err != nil {
+		return nil, err
+	}
+	if location == "" {
+		location = "us-east-1"
+	}
+	target := s.objectURL(bucket, key)
+	target.RawQuery = s3utils.QueryEncode(query)
+
+	req, err := http.NewRequest(method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.ContentLength = size
+	creds, err := s.creds.Get()
+	if err != nil {
+		return nil, err
+	}
+	req = signer.SignV4(*req, creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken, location)
+
+	resp, err := (&http.Client{Transport: s.transport}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, rawErrorResponse(resp, bucket, key)
+	}
+	return resp, nil
+}
+
+// key returns the key of path in the bucket, see WithKeyPrefix, WithKeyNormalizer and
+// WithKeyHashing.
+func (s *service) key(path string) string {
+	return s.opts.keyPrefix + s.opts.hashedKey(s.normalizeKey(path))
+}
+
+// normalizeKey applies the key normalizer to path, see WithKeyNormalizer.
+func (s *service) normalizeKey(path string) string {
+	if s.opts.keyNormalizer == nil {
+		return path
+	}
+	return s.opts.keyNormalizer(path)
+}
+
+// stripKey returns the path of a key in the bucket as seen by callers of the service.
+func (s *service) stripKey(key string) string {
+	return s.opts.stripKey(key)
+}
+
+// objectURL returns the URL of key in bucket, with the bucket as subdomain of the endpoint for
+// WithVirtualHostedStyle. IP endpoints like [2001:db8::1] have no subdomains and always get the
+// bucket in the path.
+func (s *service) objectURL(bucket, key string) *url.URL {
+	u := s.s3Client.EndpointURL()
+	if s.opts.virtualHosted && net.ParseIP(u.Hostname()) == nil {
+		u.Host = bucket + "." + u.Host
+		setObjectPath(u, "/", key)
+	} else {
+		setObjectPath(u, "/"+bucket+"/", key)
+	}
+	return u
+}
+
+// setObjectPath sets the path of u to key below prefix. The key is percent-encoded like minio
+// encodes it for signing, so spaces, '+', '&' and non-ASCII characters survive every tool.
+func setObjectPath(u *url.URL, prefix, key string) {
+	u.Path = prefix + key
+	u.RawPath = prefix + s3utils.EncodePath(key)
+}
+
+func rawErrorResponse(resp *http.Response, bucketName, key string) error {
+	errResp := minio.ErrorResponse{}
+	data, _ := ioutil.ReadAll(resp.Body)
+	if err := xml.Unmarshal(data, &errResp); err != nil {
+		errResp.Code = resp.Status
+		errResp.Message = resp.Status
+	}
+	errResp.StatusCode = resp.StatusCode
+	errResp.BucketName = bucketName
+	errResp.Key = key
+	if errResp.RequestID == "" {
+		errResp.RequestID = resp.Header.Get("x-amz-request-id")
+	}
+	if errResp.HostID == "" {
+		errResp.HostID = resp.Header.Get("x-amz-id-2")
+	}
+	return errResp
+}
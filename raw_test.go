@@ -0,0 +1,49 @@
+package s3
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRawRequestsUseConfiguredRegion(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	// credentials restricted to objects can't request the bucket location
+	fake.fail = func(r *http.Request) int {
+		if _, ok := r.URL.Query()["location"]; ok {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	fake.put("file.txt", []byte("data"), nil)
+	service := fake.newService(WithSkipBucketCheck(), WithRegion(fakeRegion))
+	info, err := service.StatFile("file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 4 {
+		t.Errorf("StatFile returned %d bytes, want 4", info.Size)
+	}
+}
+
+func TestObjectURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		opts     []Option
+		want     string
+	}{
+		{"obs.eu-de.otc.t-systems.com", nil, "https://obs.eu-de.otc.t-systems.com/bucket/a%20b/c.txt"},
+		{"obs.eu-de.otc.t-systems.com", []Option{WithVirtualHostedStyle()}, "https://bucket.obs.eu-de.otc.t-systems.com/a%20b/c.txt"},
+		{"http://127.0.0.1:9000", []Option{WithVirtualHostedStyle()}, "http://127.0.0.1:9000/bucket/a%20b/c.txt"},
+	}
+	for _, test := range tests {
+		opts := append([]Option{WithSkipBucketCheck(), WithRegion("eu-de")}, test.opts...)
+		s, err := NewService(test.endpoint, fakeAccessKey, fakeSecretKey, "bucket", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.(*service).objectURL("bucket", "a b/c.txt").String(); got != test.want {
+			t.Errorf("objectURL with %s = %s, want %s", test.endpoint, got, test.want)
+		}
+	}
+}
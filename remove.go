@@ -0,0 +1,115 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// maxRemoveBatchSize is the most keys a single multi-object delete request may contain.
+const maxRemoveBatchSize = 1000
+
+// RemoveOption configures RemoveFiles.
+type RemoveOption func(*removeOptions)
+
+type removeOptions struct {
+	batchSize int
+	progress  func(key string)
+}
+
+// WithRemoveBatchSize sets how many keys are deleted per request, at most 1000, which is also
+// the default. Batches are sent one after another, so smaller batches put less load on the
+// endpoint at a time.
+func WithRemoveBatchSize(n int) RemoveOption {
+	return func(o *removeOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithRemoveProgress calls progress for every deleted key. It is called from a single
+// goroutine, in the order the deletions are confirmed.
+func WithRemoveProgress(progress func(key string)) RemoveOption {
+	return func(o *removeOptions) {
+		o.progress = progress
+	}
+}
+
+// RemoveFileIfMatch deletes path only if its ETag is etag, otherwise a *PreconditionFailedError
+// is returned. Deletes can't be conditional in S3, so the ETag is checked right before the
+// delete; a change in between isn't detected. Missing objects fail with ErrNotFound.
+func (s *service) RemoveFileIfMatch(path, etag string) error {
+	var info ObjectInfo
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		info, err = s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return fmt.Errorf("%w: %s: %v", ErrNotFound, path, err)
+		}
+		return err
+	}
+	if expected := strings.Trim(etag, "\""); info.ETag != expected {
+		return &PreconditionFailedError{Key: path, Condition: fmt.Sprintf("ETag is %q, expected %q", info.ETag, expected)}
+	}
+	return s.RemoveFile(path)
+}
+
+// RemoveFiles deletes paths in batches using multi-object delete requests. Keys that couldn't
+// be deleted are reported in a *BatchError, keys that don't exist count as deleted.
+func (s *service) RemoveFiles(paths []string, opts ...RemoveOption) error {
+	o := removeOptions{batchSize: maxRemoveBatchSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize < 1 || o.batchSize > maxRemoveBatchSize {
+		return fmt.Errorf("s3 remove batch size must be between 1 and %d, got %d", maxRemoveBatchSize, o.batchSize)
+	}
+	errs := map[string]error{}
+	for start := 0; start < len(paths); start += o.batchSize {
+		end := start + o.batchSize
+		if end > len(paths) {
+			end = len(paths)
+		}
+		batch := paths[start:end]
+		batchErrs := s.removeKeys(batch)
+		for _, key := range batch {
+			if err, failed := batchErrs[key]; failed {
+				errs[key] = err
+			} else if o.progress != nil {
+				o.progress(key)
+			}
+		}
+	}
+	return newBatchError("remove", errs)
+}
+
+// removeKeys deletes keys with multi-object delete requests and returns the errors by key.
+func (s *service) removeKeys(keys []string) map[string]error {
+	start := time.Now()
+	keysCh := make(chan string)
+	go func() {
+		defer close(keysCh)
+		for _, key := range keys {
+			keysCh <- s.key(key)
+		}
+	}()
+	// report errors by the keys of the caller, the normalized keys may differ
+	callerKeys := map[string]string{}
+	for _, key := range keys {
+		callerKeys[s.key(key)] = key
+	}
+	errs := map[string]error{}
+	for removeErr := range s.s3Client.RemoveObjects(s.bucketName, keysCh) {
+		errs[callerKeys[removeErr.ObjectName]] = s.wrapError(removeErr.Err)
+	}
+	for _, key := range keys {
+		s.invalidate(key)
+		s.emit(Event{Op: EventDelete, Key: key, Err: errs[key]}, start)
+	}
+	return errs
+}
@@ -0,0 +1,156 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// bucketARNPrefix is prepended to destination buckets given by name.
+const bucketARNPrefix = "arn:aws:s3:::"
+
+// ReplicationConfig replicates new objects of the bucket to other buckets, e.g. in a second
+// region. Role is the agency (IAM role) GOBS assumes to write to the destinations.
+type ReplicationConfig struct {
+	Role  string
+	Rules []ReplicationRule
+}
+
+// ReplicationRule replicates the objects under Prefix to DestinationBucket, which is either a
+// bucket name or its ARN. StorageClass of the replicas is optional.
+type ReplicationRule struct {
+	ID                string
+	Prefix            string
+	Enabled           bool
+	DestinationBucket string
+	StorageClass      string
+}
+
+type replicationConfiguration struct {
+	XMLName xml.Name          `xml:"ReplicationConfiguration"`
+	Role    string            `xml:"Role"`
+	Rules   []replicationRule `xml:"Rule"`
+}
+
+type replicationRule struct {
+	ID          string                 `xml:"ID,omitempty"`
+	Status      string                 `xml:"Status"`
+	Prefix      string                 `xml:"Prefix"`
+	Destination replicationDestination `xml:"Destination"`
+}
+
+type replicationDestination struct {
+	Bucket       string `xml:"Bucket"`
+	StorageClass string `xml:"StorageClass,omitempty"`
+}
+
+func (c ReplicationConfig) validate(bucketName string) error {
+	if strings.TrimSpace(c.Role) == "" {
+		return errors.New("replication role must not be empty")
+	}
+	if len(c.Rules) == 0 {
+		return errors.New("replication needs at least one rule")
+	}
+	ids := map[string]bool{}
+	for _, rule := range c.Rules {
+		destination := strings.TrimPrefix(rule.DestinationBucket, bucketARNPrefix)
+		if strings.TrimSpace(destination) == "" {
+			return fmt.Errorf("replication rule %s has no destination bucket", rule.ID)
+		}
+		if destination == bucketName {
+			return fmt.Errorf("replication rule %s can't replicate the bucket to itself", rule.ID)
+		}
+		if rule.ID != "" && ids[rule.ID] {
+			return fmt.Errorf("replication rule ID %s is used more than once", rule.ID)
+		}
+		ids[rule.ID] = true
+	}
+	return nil
+}
+
+// SetReplication replaces the replication configuration of the bucket with config. Versioning
+// has to be enabled on the bucket and on all destinations.
+func (s *service) SetReplication(config ReplicationConfig) error {
+	if err := config.validate(s.bucketName); err != nil {
+		return err
+	}
+	replication := replicationConfiguration{Role: config.Role}
+	for _, rule := range config.Rules {
+		status := "Disabled"
+		if rule.Enabled {
+			status = "Enabled"
+		}
+		destination := rule.DestinationBucket
+		if !strings.HasPrefix(destination, bucketARNPrefix) {
+			destination = bucketARNPrefix + destination
+		}
+		replication.Rules = append(replication.Rules, replicationRule{
+			ID:     rule.ID,
+			Status: status,
+			Prefix: rule.Prefix,
+			Destination: replicationDestination{
+				Bucket:       destination,
+				StorageClass: rule.StorageClass,
+			},
+		})
+	}
+	body, err := xml.Marshal(replication)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(body)
+	header := http.Header{}
+	header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+	query := url.Values{}
+	query.Set("replication", "")
+	start := time.Now()
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodPut, "", query, header, body)
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	s.emit(Event{Op: EventBucketConfig, Err: err}, start)
+	return err
+}
+
+// GetReplication returns the replication configuration of the bucket, or nil if replication
+// isn't configured.
+func (s *service) GetReplication() (*ReplicationConfig, error) {
+	query := url.Values{}
+	query.Set("replication", "")
+	replication := replicationConfiguration{}
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		return xml.NewDecoder(resp.Body).Decode(&replication)
+	})
+	if err != nil {
+		if errorResponse(err).Code == "ReplicationConfigurationNotFoundError" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	config := &ReplicationConfig{Role: replication.Role}
+	for _, rule := range replication.Rules {
+		config.Rules = append(config.Rules, ReplicationRule{
+			ID:                rule.ID,
+			Prefix:            rule.Prefix,
+			Enabled:           rule.Status == "Enabled",
+			DestinationBucket: strings.TrimPrefix(rule.Destination.Bucket, bucketARNPrefix),
+			StorageClass:      rule.Destination.StorageClass,
+		})
+	}
+	return config, nil
+}
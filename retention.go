@@ -0,0 +1,65 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// RetentionMode is the object lock mode of a retention period.
+type RetentionMode = minio.RetentionMode
+
+const (
+	RetentionGovernance = minio.Governance
+	RetentionCompliance = minio.Compliance
+)
+
+// DefaultRetention is the retention applied to new objects of an object lock enabled bucket.
+// Only one of Days and Years is set.
+type DefaultRetention struct {
+	Mode  RetentionMode
+	Days  int
+	Years int
+}
+
+// SetDefaultRetention makes every object uploaded to the bucket inherit a retention of days in
+// the given mode. The bucket must have been created with object lock enabled. A retention set
+// explicitly on an object takes precedence over the default.
+func (s *service) SetDefaultRetention(mode RetentionMode, days int) error {
+	if !mode.IsValid() {
+		return fmt.Errorf("invalid retention mode %s", mode)
+	}
+	if days < 1 {
+		return fmt.Errorf("retention must be at least one day, got %d", days)
+	}
+	config := &objectLockConfiguration{ObjectLockEnabled: "Enabled", Rule: &objectLockRule{}}
+	config.Rule.DefaultRetention.Mode = mode
+	config.Rule.DefaultRetention.Days = days
+	defer s.resetBucketStatus()
+	start := time.Now()
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		return s.putObjectLock(ctx, config)
+	})
+	s.emit(Event{Op: EventBucketConfig, Err: err}, start)
+	return err
+}
+
+// GetDefaultRetention returns the default retention of the bucket, or nil if there is none.
+func (s *service) GetDefaultRetention() (*DefaultRetention, error) {
+	var config *objectLockConfiguration
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		config, err = s.getObjectLock(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if config.Rule == nil {
+		return nil, nil
+	}
+	retention := config.Rule.DefaultRetention
+	return &DefaultRetention{Mode: retention.Mode, Days: retention.Days, Years: retention.Years}, nil
+}
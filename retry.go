@@ -0,0 +1,274 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+const (
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// BackoffStrategy computes the delay before retry number attempt, starting at 0.
+type BackoffStrategy interface {
+	NextDelay(attempt int) time.Duration
+}
+
+// BackoffFunc adapts a function to a BackoffStrategy.
+type BackoffFunc func(attempt int) time.Duration
+
+func (f BackoffFunc) NextDelay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// ConstantBackoff waits the same delay before every retry.
+type ConstantBackoff time.Duration
+
+func (b ConstantBackoff) NextDelay(attempt int) time.Duration {
+	return time.Duration(b)
+}
+
+// ExponentialBackoff doubles the delay with every attempt, starting at Base and capped at Max,
+// and picks a random delay between zero and that value ("full jitter").
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Base << uint(attempt)
+	if delay <= 0 || delay > b.Max {
+		delay = b.Max
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+var defaultBackoff = ExponentialBackoff{Base: retryBaseDelay, Max: retryMaxDelay}
+
+// WithRetry retries failed operations on throttling, server and network errors up to
+// maxAttempts times in total. A Retry-After header sent by the server is honored, otherwise
+// the delay grows exponentially. Without WithRetry minio-go retries failed requests itself, up
+// to minio.MaxRetry times; with it those internal retries are stopped for the operations of
+// the service, so maxAttempts is the total.
+func WithRetry(maxAttempts int) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+	}
+}
+
+// WithBackoff sets the delay strategy between retries. The default is exponential backoff with
+// full jitter starting at 100ms and capped at 10s.
+func WithBackoff(strategy BackoffStrategy) Option {
+	return func(o *options) {
+		o.backoff = strategy
+	}
+}
+
+// WithMaxRetryDuration stops retrying once the total time spent on an operation, including
+// delays, would exceed d. Zero means no limit.
+func WithMaxRetryDuration(d time.Duration) Option {
+	return func(o *options) {
+		o.maxRetryDuration = d
+	}
+}
+
+type retryHintKey struct{}
+
+// retryHint carries the Retry-After delay of the last throttled response of an attempt
+// from the transport back to the retry loop. With WithRetry it also stops the attempt on the
+// first response minio-go would retry: cancel aborts its retry loop, which then returns
+// context.Canceled, so err keeps the actual failure.
+type retryHint struct {
+	mu     sync.Mutex
+	after  time.Duration
+	set    bool
+	cancel func()
+	err    error
+}
+
+func (h *retryHint) store(after time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.after = after
+	h.set = true
+}
+
+func (h *retryHint) load() (time.Duration, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.after, h.set
+}
+
+// stop ends the attempt with err, if the retries of minio-go are to be stopped.
+func (h *retryHint) stop(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cancel == nil || h.err != nil {
+		return
+	}
+	h.err = err
+	h.cancel()
+}
+
+func (h *retryHint) failure() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// minioRetryableCodes are the error codes minio-go retries regardless of the status code.
+var minioRetryableCodes = map[string]bool{
+	"RequestError":          true,
+	"RequestTimeout":        true,
+	"Throttling":            true,
+	"ThrottlingException":   true,
+	"RequestLimitExceeded":  true,
+	"RequestThrottled":      true,
+	"InternalError":         true,
+	"ExpiredToken":          true,
+	"ExpiredTokenException": true,
+	"SlowDown":              true,
+}
+
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	hint, _ := req.Context().Value(retryHintKey{}).(*retryHint)
+	resp, err := t.base.RoundTrip(req)
+	if uploads, ok := req.Context().Value(createdUploadsKey{}).(*createdUploads); ok && err == nil {
+		if err := uploads.record(req, resp); err != nil {
+			return nil, err
+		}
+	}
+	if hint == nil {
+		return resp, err
+	}
+	if err != nil {
+		if req.Context().Err() == nil {
+			// http.Client returns transport errors as *url.Error
+			op := req.Method[:1] + strings.ToLower(req.Method[1:])
+			hint.stop(&url.Error{Op: op, URL: req.URL.String(), Err: err})
+		}
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusTooManyRequests {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			hint.store(after)
+		}
+	}
+	if hint.cancel == nil {
+		return resp, nil
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusPartialContent:
+		return resp, nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	errResp := minio.ToErrorResponse(rawErrorResponse(&http.Response{
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       ioutil.NopCloser(bytes.NewReader(data)),
+	}, "", ""))
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		hint.stop(errResp)
+	default:
+		if minioRetryableCodes[errResp.Code] {
+			hint.stop(errResp)
+		}
+	}
+	return resp, nil
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		after := date.Sub(now)
+		if after < 0 {
+			after = 0
+		}
+		return after, true
+	}
+	return 0, false
+}
+
+func isRetryable(err error) bool {
+	resp := errorResponse(err)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	if resp.Code == "SlowDown" {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retry runs op until it succeeds, fails with a non-retryable error or the attempts are used up.
+func (s *service) retry(ctx context.Context, op func(ctx context.Context) error) error {
+	ctx, cancel := s.transferContext(ctx)
+	defer cancel()
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancelAttempt := context.WithCancel(ctx)
+		hint := &retryHint{}
+		if s.opts.maxAttempts > 0 {
+			hint.cancel = cancelAttempt
+		}
+		err := op(context.WithValue(attemptCtx, retryHintKey{}, hint))
+		cancelAttempt()
+		if failure := hint.failure(); err != nil && failure != nil && ctx.Err() == nil {
+			err = failure
+		}
+		if err == nil || attempt+1 >= s.opts.maxAttempts || !isRetryable(err) {
+			return s.wrapError(err)
+		}
+		delay, ok := hint.load()
+		if !ok {
+			delay = s.opts.backoff.NextDelay(attempt)
+		}
+		if s.opts.maxRetryDuration > 0 && time.Since(start)+delay > s.opts.maxRetryDuration {
+			return s.wrapError(err)
+		}
+		s.logger.Printf("s3: attempt %d failed, retrying in %s: %v", attempt+1, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return s.wrapError(err)
+		case <-timer.C:
+		}
+	}
+}
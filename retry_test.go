@@ -0,0 +1,46 @@
+package s3
+
+import (
+	"bytes"
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRetryAttempts(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		requests int32
+		wantErr  bool
+	}{
+		// minio-go retries the request itself
+		{"default", nil, 2, false},
+		{"single attempt", []Option{WithRetry(1)}, 1, true},
+		{"two attempts", []Option{WithRetry(2), WithBackoff(ConstantBackoff(0))}, 2, false},
+	}
+	for _, test := range tests {
+		fake := newFakeS3(t)
+		fake.put("a.txt", []byte("a"), nil)
+		var requests int32
+		fake.fail = func(r *http.Request) int {
+			if r.Method == http.MethodGet && r.URL.Path == "/"+fakeBucket+"/a.txt" && atomic.AddInt32(&requests, 1) == 1 {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		}
+		buf := &bytes.Buffer{}
+		_, err := fake.newService(test.opts...).DownloadTo("a.txt", buf)
+		if test.wantErr {
+			if resp := errorResponse(err); resp.StatusCode != http.StatusServiceUnavailable {
+				t.Errorf("%s: error is %v, want the server error", test.name, err)
+			}
+		} else if err != nil || buf.String() != "a" {
+			t.Errorf("%s: downloaded %q, %v", test.name, buf.String(), err)
+		}
+		if got := atomic.LoadInt32(&requests); got != test.requests {
+			t.Errorf("%s: sent %d requests, want %d", test.name, got, test.requests)
+		}
+		fake.Close()
+	}
+}
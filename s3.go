@@ -1,153 +1,789 @@
 package s3
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	netUrl "net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/minio/minio-go/v6"
+	"github.com/minio/minio-go/v6/pkg/credentials"
 )
 
 const (
-	ContentTypeJSON = "application/json"
-	ContentTypePDF  = "application/pdf"
-	ContentTypePNG  = "image/png"
-	ContentTypeJPEG = "image/jpeg"
+	ContentTypeJSON   = "application/json"
+	ContentTypeNDJSON = "application/x-ndjson"
+	ContentTypePDF    = "application/pdf"
+	ContentTypePNG    = "image/png"
+	ContentTypeJPEG   = "image/jpeg"
 )
 
+const (
+	libraryName = "otc-gobs"
+	modulePath  = "github.com/MaxBreida/otc-gobs"
+)
+
+type ObjectInfo = minio.ObjectInfo
+
+// BucketInfo holds the name and creation date of a bucket.
+type BucketInfo = minio.BucketInfo
+
+// Usage is the number of objects and the total size of a bucket.
+type Usage struct {
+	Objects int64
+	Bytes   int64
+}
+
 type Service interface {
 	AddLifeCycleRule(ruleId, folderPath string, daysToExpiry int) error
-	UploadFile(path, contentType string, data io.Reader, objectSize *int64) error
-	GetFileUrl(path string, expiration time.Duration) (*url.URL, error)
+	AddLifeCycleRuleWithFilter(ruleId string, filter LifecycleFilter, daysToExpiry int) error
+	AddAbortIncompleteUploadRule(ruleId, prefix string, daysAfterInitiation int) error
+	UploadFile(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) error
+	UploadFileWithOptions(path string, data io.Reader, objectSize *int64, opts minio.PutObjectOptions) error
+	UploadFileWithResult(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) (*UploadResult, error)
+	UploadFileWithSHA256(path, contentType string, data io.Reader, size int64, sum string, opts ...UploadOption) error
+	UploadLocalFile(localPath, path, contentType string, opts ...UploadOption) error
+	UploadBatch(ctx context.Context, items map[string]UploadItem) error
+	InitiateUpload(path, contentType string) (string, error)
+	UploadPart(uploadID string, partNumber int, data io.Reader) error
+	CompleteUpload(uploadID string) error
+	AbortUpload(uploadID string) error
+	UploadFileAsync(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) *UploadHandle
+	GetFileUrl(path string, expiration time.Duration, opts ...PresignOption) (*url.URL, error)
+	GetFileUrlString(path string, expiration time.Duration, opts ...PresignOption) (string, error)
+	GetFileUrls(ctx context.Context, paths []string, expiration time.Duration, opts ...PresignOption) (map[string]*url.URL, error)
+	PublicURL(path string) (*url.URL, error)
+	VerifyURL(u *url.URL) error
+	ClockSkew() (time.Duration, error)
+	GenerateUploadForm(keyPrefix string, maxSize int64, allowedTypes []string, expiry time.Duration) (*UploadForm, error)
+	UploadNDJSON(path string, records <-chan interface{}) error
+	UploadWriter(path, contentType string) (io.WriteCloser, error)
 	UploadJSONFileWithLink(path string, data io.Reader, linkExpiration time.Duration) (*url.URL, error)
-	DownloadFile(path, localPath string) error
-	DownloadDirectory(path, localPath string) error
+	AppendToObject(path string, data io.Reader) error
+	DownloadFile(path, localPath string, opts ...DownloadOption) error
+	DownloadFileParallel(path, localPath string, parts int) error
+	DownloadResume(path, localPath string) error
+	DrainFile(path, localPath string) error
+	DownloadIfModified(path, localPath string, since time.Time) (bool, error)
+	DownloadIfETagDiffers(path, localPath, etag string) (bool, error)
+	DownloadDirectory(path, localPath string, opts ...DirectoryOption) error
+	DownloadDirectoryWithResult(path, localPath string, opts ...DirectoryOption) (*DownloadResult, error)
+	UploadDirectory(localPath, path string, opts ...DirectoryOption) error
 	DownloadFileBytes(path string) ([]byte, error)
+	DownloadTo(path string, w io.Writer) (int64, error)
+	DownloadStreamVerified(path string) (io.ReadCloser, error)
+	DownloadFileBuffer(path string) (*Buffer, error)
+	DownloadFileMmap(path string) (*MmapHandle, error)
+	DownloadVersionBytes(path, versionID string) ([]byte, error)
+	DownloadVersionRange(path, versionID string, offset, length int64) ([]byte, error)
+	ListObjects(prefix string) ([]ObjectInfo, error)
+	IterateObjects(ctx context.Context, prefix string) *ObjectIterator
+	ForEachObject(ctx context.Context, prefix string, recursive bool, fn func(ObjectInfo) error) error
+	ListDir(prefix string) (*DirListing, error)
+	FileExists(path string) (bool, error)
+	FilesExist(paths []string) (map[string]bool, error)
+	WaitForObject(ctx context.Context, path string, pollInterval time.Duration) error
+	CopyFile(srcPath, dstPath string, opts ...CopyOption) error
+	CopyToBucket(srcPath, dstBucket, dstPath string, opts ...CopyOption) error
+	CopyDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error
+	MoveDirectory(srcPrefix, dstPrefix string, opts ...DirectoryOption) error
+	CopyChanged(srcPrefix, dstPrefix string, opts ...DirectoryOption) (*CopyResult, error)
+	UpdateMetadata(path string, metadata map[string]string, contentType string) error
+	FixContentTypes(prefix string) (int, error)
+	StatFile(path string) (*FileInfo, error)
+	GetWebsiteRedirect(path string) (string, error)
+	CompareMetadata(pathA, pathB string) (*MetadataDiff, error)
+	GetObjectACL(path string) (*ObjectACL, error)
+	SetObjectACL(path, acl string) error
+	Touch(path string) error
+	TagPrefix(prefix string, tags map[string]string) error
+	FindByTag(prefix, tagKey, tagValue string) ([]string, error)
 	RemoveFile(path string) error
+	SoftDelete(path string) error
+	Undelete(path string) error
+	RemoveFileIfMatch(path, etag string) error
+	RemoveFiles(paths []string, opts ...RemoveOption) error
+	RemoveMatching(prefix, pattern string) ([]string, error)
+	EmptyTrash(olderThan time.Duration) (int, error)
+	BucketUsage() (*Usage, error)
+	BucketStatus() (*BucketStatus, error)
+	ExportManifest(prefix string, w io.Writer) error
+	DownloadManifest(manifestPath, localRoot string) error
+	GetBucketRegion() (string, error)
+	ListBuckets() ([]BucketInfo, error)
+	Raw() *minio.Client
+	SetDefaultRetention(mode RetentionMode, days int) error
+	GetDefaultRetention() (*DefaultRetention, error)
+	SetReplication(config ReplicationConfig) error
+	GetReplication() (*ReplicationConfig, error)
+	Reconfigure(accessKey, secret, token string, verify bool) error
 }
 
+// service is safe for concurrent use: its fields are not modified after NewService, except
+// for the ones guarded by their own mutex. Shared maps like urlValues must only be read, use
+// presignOptions for a modifiable copy.
 type service struct {
-	s3Client       *minio.Client
-	lifeCycleRules string
-	bucketName     string
-	urlValues      url.Values
+	s3Client    *minio.Client
+	bucketName  string
+	urlValues   url.Values
+	logger      Logger
+	opts        options
+	existsCache *existenceCache
+	bytesCache  *bytesCache
+	creds       *credentials.Credentials
+	provider    *rotatingCredentials
+	transport   http.RoundTripper
+	stopped     <-chan struct{}
+	buffers     *sync.Pool
+
+	reconfigureMu sync.Mutex
+
+	regionMu sync.Mutex
+	region   string
+
+	statusMu   sync.Mutex
+	status     *BucketStatus
+	statusTime time.Time
 }
 
-func NewService(url, accessKey, accessSecret, bucketName string) (Service, error) {
-	s3Client, err := minio.New(url, accessKey, accessSecret, true)
+func NewService(url, accessKey, accessSecret, bucketName string, opts ...Option) (Service, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = nopLogger{}
+	}
+	if o.backoff == nil {
+		o.backoff = defaultBackoff
+	}
+	if o.appName == "" || o.appVersion == "" {
+		o.appName, o.appVersion = libraryName, libraryVersion()
+	}
+	if o.partSize != 0 {
+		if err := validPartSize(o.partSize); err != nil {
+			return nil, err
+		}
+	}
+	if err := validListPageSize(o.listPageSize); err != nil {
+		return nil, err
+	}
+	if o.defaultStorageClass != "" {
+		if _, err := s3StorageClass(o.defaultStorageClass); err != nil {
+			return nil, err
+		}
+	}
+	if err := validKeyHashing(&o); err != nil {
+		return nil, err
+	}
+	for key := range o.defaultMetadata {
+		if err := checkUserMetadataKey(key); err != nil {
+			return nil, err
+		}
+	}
+	url, secure, err := endpointScheme(url, !o.plainHTTP)
 	if err != nil {
 		return nil, err
 	}
-	exists, err := s3Client.BucketExists(bucketName)
+	provider := newRotatingCredentials(accessKey, accessSecret, "")
+	creds := credentials.New(provider)
+	lookup := minio.BucketLookupAuto
+	if o.virtualHosted {
+		lookup = minio.BucketLookupDNS
+	}
+	s3Client, err := minio.NewWithOptions(url, &minio.Options{Creds: creds, Secure: secure, Region: o.region, BucketLookup: lookup})
 	if err != nil {
 		return nil, err
 	}
-	if !exists {
-		return nil, fmt.Errorf("s3 bucket required for service (%s) doesn't exist", bucketName)
+	transport, err := newTransport(&o)
+	if err != nil {
+		return nil, err
+	}
+	if o.transferCounter != nil {
+		transport = &countingTransport{base: transport, counter: o.transferCounter, bucketName: bucketName, stripKey: o.stripKey}
+	}
+	s3Client.SetCustomTransport(transport)
+	s3Client.SetAppInfo(o.appName, o.appVersion)
+	if !o.skipBucketCheck {
+		endpoint := s3Client.EndpointURL().Host
+		exists, err := s3Client.BucketExists(bucketName)
+		if err != nil {
+			return nil, newStartupError(endpoint, bucketName, endpointRegion(endpoint), err)
+		}
+		if !exists {
+			return nil, &StartupError{
+				Endpoint: endpoint,
+				Bucket:   bucketName,
+				Region:   endpointRegion(endpoint),
+				Kind:     ErrBucketNotFound,
+			}
+		}
 	}
 	urlValues := make(netUrl.Values)
-	urlValues.Set("response-content-disposition", "inline")
+	urlValues.Set(dispositionParam, "inline")
+	if o.contentDisposition != "" {
+		urlValues.Set(dispositionParam, o.contentDisposition)
+	}
 	return &service{
-		s3Client:       s3Client,
-		lifeCycleRules: "",
-		bucketName:     bucketName,
-		urlValues:      urlValues,
+		s3Client:    s3Client,
+		bucketName:  bucketName,
+		urlValues:   urlValues,
+		logger:      o.logger,
+		opts:        o,
+		existsCache: newExistenceCache(o.existenceCacheTTL),
+		bytesCache:  newBytesCache(o.downloadCacheSize),
+		creds:       creds,
+		provider:    provider,
+		transport:   transport,
+		stopped:     watchShutdown(o.shutdownCtx, o.shutdownGrace),
+		buffers:     newBufferPool(o.bufferPool),
+		region:      o.region,
 	}, nil
 }
 
+// endpointScheme strips an "http://" or "https://" scheme from endpoint and returns whether to
+// use HTTPS. Without a scheme, secure is used as it is.
+func endpointScheme(endpoint string, secure bool) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		if !secure {
+			return "", false, fmt.Errorf("s3 endpoint %s uses https but WithPlainHTTP is set", endpoint)
+		}
+		return strings.TrimSuffix(strings.TrimPrefix(endpoint, "https://"), "/"), true, nil
+	case strings.HasPrefix(endpoint, "http://"):
+		return strings.TrimSuffix(strings.TrimPrefix(endpoint, "http://"), "/"), false, nil
+	}
+	return endpoint, secure, nil
+}
+
+// Raw returns the underlying minio client for APIs this package doesn't cover. Requests made
+// with it bypass the retries, logging, error wrapping, caches and defaults of the service.
+func (s *service) Raw() *minio.Client {
+	return s.s3Client
+}
+
+// libraryVersion returns the version of this module the binary was built with.
+func libraryVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				return dep.Version
+			}
+		}
+	}
+	return "devel"
+}
+
 func (s *service) AddLifeCycleRule(ruleId, folderPath string, daysToExpiry int) error {
+	if s.opts.keyHash != nil {
+		return errKeyHashing
+	}
 	if !strings.HasSuffix(folderPath, "/") {
 		folderPath = folderPath + "/"
 	}
-	lifeCycleString := fmt.Sprintf(
-		`<LifecycleConfiguration><Rule><ID>%s</ID><Prefix>%s</Prefix><Status>Enabled</Status><Expiration><Days>%d</Days></Expiration></Rule></LifecycleConfiguration>`,
-		ruleId, folderPath, daysToExpiry)
-	return s.s3Client.SetBucketLifecycle(s.bucketName, lifeCycleString)
+	folderPath = s.key(folderPath)
+	return s.putLifecycleRule(lifecycleRule{
+		ID:         ruleId,
+		Prefix:     &folderPath,
+		Status:     "Enabled",
+		Expiration: &lifecycleExpiration{Days: daysToExpiry},
+	})
 }
 
-func (s *service) UploadFile(path, contentType string, data io.Reader, objectSize *int64) error {
+func (s *service) UploadFile(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) error {
 	size := int64(-1)
 	if objectSize != nil {
 		size = *objectSize
 	}
-	_, err := s.s3Client.PutObject(s.bucketName, path, data, size, minio.PutObjectOptions{ContentType: contentType})
-	return err
+	return s.uploadFile(context.Background(), path, data, size, newUploadOptions(contentType, opts))
 }
 
-func (s *service) GetFileUrl(path string, expiration time.Duration) (*url.URL, error) {
-	return s.s3Client.PresignedGetObject(s.bucketName, path, expiration, s.urlValues)
+// uploadFile is UploadFile with the context of the upload.
+func (s *service) uploadFile(ctx context.Context, path string, data io.Reader, size int64, o uploadOptions) error {
+	if o.verifySize && size >= 0 {
+		// the transport rejects a body shorter than its content length before it is stored
+		if remaining, ok := remainingBytes(data); ok && remaining != size {
+			return &SizeMismatchError{Key: path, Expected: size, Actual: remaining}
+		}
+	}
+	if o.backup {
+		if err := s.backupObject(path); err != nil {
+			return err
+		}
+	}
+	n, err := s.putObject(ctx, path, data, size, o.put)
+	if err != nil || !o.verifySize || size < 0 || n == size {
+		return err
+	}
+	if o.removeMismatched {
+		if err := s.RemoveFile(path); err != nil {
+			s.logger.Printf("s3: failed to remove truncated upload of %s: %v", path, err)
+		}
+	}
+	return &SizeMismatchError{Key: path, Expected: size, Actual: n}
+}
+
+func (s *service) GetFileUrl(path string, expiration time.Duration, opts ...PresignOption) (*url.URL, error) {
+	return s.presignGet(path, expiration, opts)
+}
+
+func (s *service) GetFileUrlString(path string, expiration time.Duration, opts ...PresignOption) (string, error) {
+	u, err := s.GetFileUrl(path, expiration, opts...)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
 }
 
 func (s *service) UploadJSONFileWithLink(path string, data io.Reader, linkExpiration time.Duration) (*url.URL, error) {
-	_, err := s.s3Client.PutObject(s.bucketName, path, data, -1, minio.PutObjectOptions{ContentType: "application/json"})
+	_, err := s.putObject(context.Background(), path, data, -1, minio.PutObjectOptions{ContentType: "application/json"})
 	if err != nil {
 		return nil, err
 	}
-	return s.s3Client.PresignedGetObject(s.bucketName, path, 24*time.Hour, s.urlValues)
+	return s.presignGet(path, 24*time.Hour, nil)
 }
 
-func (s *service) DownloadDirectory(path, localPath string) error {
+func (s *service) UploadNDJSON(path string, records <-chan interface{}) error {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder := json.NewEncoder(pw)
+		for record := range records {
+			if err := encoder.Encode(record); err != nil {
+				pw.CloseWithError(err)
+				// drain the channel so the producer doesn't block forever
+				for range records {
+				}
+				return
+			}
+		}
+		pw.Close()
+	}()
+	_, err := s.putObject(context.Background(), path, pr, -1, minio.PutObjectOptions{ContentType: ContentTypeNDJSON})
+	pr.CloseWithError(err)
+	return err
+}
+
+// DownloadResult summarizes a directory download. Files and Bytes only count the files that
+// were downloaded successfully, Skipped the objects excluded by filters or the key mapper
+// and Failed the objects reported in the *BatchError.
+type DownloadResult struct {
+	Files   int
+	Bytes   int64
+	Skipped int
+	Failed  int
+}
+
+func (s *service) DownloadDirectory(path, localPath string, opts ...DirectoryOption) error {
+	_, err := s.DownloadDirectoryWithResult(path, localPath, opts...)
+	return err
+}
+
+// DownloadDirectoryWithResult is DownloadDirectory but also reports what was downloaded, which
+// is also accurate if some files failed.
+func (s *service) DownloadDirectoryWithResult(path, localPath string, opts ...DirectoryOption) (*DownloadResult, error) {
+	o := newDirectoryOptions(opts)
+	if o.glob != "" {
+		if _, err := pathpkg.Match(o.glob, ""); err != nil {
+			return nil, err
+		}
+	}
+	root, err := filepath.Abs(localPath)
+	if err != nil {
+		return nil, err
+	}
+	result := &DownloadResult{}
+	dir := s.normalizeKey(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 	doneCh := make(chan struct{})
 	defer close(doneCh)
-	objectCh := s.s3Client.ListObjectsV2(s.bucketName, path, true, doneCh)
+	objectCh := s.listObjects(path, doneCh)
 	wg := sync.WaitGroup{}
-	errCh := make(chan error)
+	mu := sync.Mutex{}
+	sem := make(chan struct{}, o.concurrency)
+	errs := map[string]error{}
+	used := map[string]bool{}
 	for obj := range objectCh {
 		if obj.Err != nil {
-			return obj.Err
+			wg.Wait()
+			result.Failed = len(errs)
+			return result, s.listingError("download", errs, obj.Err)
+		}
+		if !o.matches(obj, dir) {
+			result.Skipped++
+			continue
+		}
+		if s.shuttingDown() {
+			mu.Lock()
+			errs[obj.Key] = ErrShuttingDown
+			mu.Unlock()
+			break
+		}
+		if o.failFast && ctx.Err() != nil {
+			break
+		}
+		fileName, err := o.localName(obj, dir, used)
+		if err == nil && fileName != "" {
+			fileName, err = localFilePath(root, fileName)
+		}
+		if err != nil {
+			mu.Lock()
+			errs[obj.Key] = err
+			mu.Unlock()
+			if o.failFast {
+				break
+			}
+			continue
+		}
+		if fileName == "" {
+			result.Skipped++
+			continue
 		}
 		wg.Add(1)
-		go func(obj minio.ObjectInfo, errChan chan<- error) {
-			fileName := strings.TrimPrefix(obj.Key, path+"/")
-			err := s.DownloadFile(obj.Key, localPath+"/"+fileName)
+		sem <- struct{}{}
+		go func(obj minio.ObjectInfo, fileName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := s.downloadFile(ctx, obj.Key, fileName, o.download)
+			mu.Lock()
+			defer mu.Unlock()
 			if err != nil {
-				errCh <- err
+				errs[obj.Key] = err
+				if o.failFast {
+					cancel()
+				}
+				return
 			}
-			wg.Done()
-		}(obj, errCh)
+			result.Files++
+			result.Bytes += obj.Size
+		}(obj, fileName)
 	}
 	wg.Wait()
-	close(errCh)
-	errs := []error{}
-	for err := range errCh {
-		errs = append(errs, err)
+	result.Failed = len(errs)
+	return result, newBatchError("download", errs)
+}
+
+func (s *service) DownloadFile(path, localPath string, opts ...DownloadOption) error {
+	return s.downloadFile(context.Background(), path, localPath, opts)
+}
+
+func (s *service) downloadFile(ctx context.Context, path, localPath string, opts []DownloadOption) error {
+	o := downloadOptions{}
+	for _, opt := range opts {
+		opt(&o)
 	}
-	if len(errs) > 0 {
-		return fmt.Errorf("Failed to download files from s3: %v", errs)
+	getOpts := minio.GetObjectOptions{}
+	if !o.unmodifiedSince.IsZero() {
+		if err := getOpts.SetUnmodified(o.unmodifiedSince); err != nil {
+			return err
+		}
+	}
+	if o.ifMatch != "" {
+		if err := getOpts.SetMatchETag(o.ifMatch); err != nil {
+			return err
+		}
+	}
+	var info ObjectInfo
+	if o.preserveModTime || o.verifyChecksum || o.encryptionContext != nil {
+		var err error
+		info, err = s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		if err != nil {
+			return s.wrapError(err)
+		}
+		if o.encryptionContext != nil {
+			if err := checkEncryptionContext(path, info.Metadata, o.encryptionContext); err != nil {
+				return err
+			}
+		}
+		if o.ifMatch != "" && info.ETag != o.ifMatch {
+			return &PreconditionFailedError{Key: path, Condition: o.condition()}
+		}
+		// make sure the downloaded data belongs to the stat
+		if err := getOpts.SetMatchETag(info.ETag); err != nil {
+			return err
+		}
+	}
+	err := s.retry(ctx, func(ctx context.Context) error {
+		if s.opts.tempDir != "" {
+			return s.getObjectViaTemp(ctx, path, localPath, getOpts)
+		}
+		return s.s3Client.FGetObjectWithContext(ctx, s.bucketName, s.key(path), localPath, getOpts)
+	})
+	if errorResponse(err).StatusCode == http.StatusPreconditionFailed {
+		return &PreconditionFailedError{Key: path, Condition: o.condition()}
+	}
+	if err != nil {
+		return err
+	}
+	if o.verifyChecksum {
+		if err := verifyFile(localPath, info); err != nil {
+			os.Remove(localPath)
+			return err
+		}
+	}
+	if o.preserveModTime {
+		return os.Chtimes(localPath, time.Now(), info.LastModified)
 	}
 	return nil
 }
 
-func (s *service) DownloadFile(path, localPath string) error {
-	return s.s3Client.FGetObject(s.bucketName, path, localPath, minio.GetObjectOptions{})
+func (s *service) DownloadIfModified(path, localPath string, since time.Time) (bool, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetModified(since); err != nil {
+		return false, err
+	}
+	return s.downloadConditional(path, localPath, opts)
+}
+
+func (s *service) DownloadIfETagDiffers(path, localPath, etag string) (bool, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetMatchETagExcept(etag); err != nil {
+		return false, err
+	}
+	return s.downloadConditional(path, localPath, opts)
+}
+
+func (s *service) downloadConditional(path, localPath string, opts minio.GetObjectOptions) (bool, error) {
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		return s.s3Client.FGetObjectWithContext(ctx, s.bucketName, s.key(path), localPath, opts)
+	})
+	if isNotModified(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 func (s *service) DownloadFileBytes(path string) ([]byte, error) {
-	object, err := s.s3Client.GetObject(s.bucketName, path, minio.GetObjectOptions{})
+	var buffer []byte
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		buffer, err = s.cachedFileBytes(ctx, path)
+		return err
+	})
+	if isNotFound(err) {
+		return nil, fmt.Errorf("%w: %s: %v", ErrNotFound, path, err)
+	}
+	return buffer, err
+}
+
+// cachedFileBytes downloads path, or returns a copy of the cached data if it didn't change, see
+// WithDownloadCache.
+func (s *service) cachedFileBytes(ctx context.Context, path string) ([]byte, error) {
+	opts := minio.GetObjectOptions{}
+	key := s.normalizeKey(path)
+	cached, etag, ok := s.bytesCache.get(key)
+	if ok {
+		if err := opts.SetMatchETagExcept(etag); err != nil {
+			return nil, err
+		}
+	}
+	buffer, info, err := s.downloadFileBytes(ctx, path, opts)
+	if ok && isNotModified(err) {
+		return append([]byte(nil), cached...), nil
+	}
 	if err != nil {
 		return nil, err
 	}
+	if s.bytesCache != nil {
+		s.bytesCache.set(key, info.ETag, append([]byte(nil), buffer...))
+	}
+	return buffer, nil
+}
+
+func (s *service) downloadFileBytes(ctx context.Context, path string, opts minio.GetObjectOptions) ([]byte, ObjectInfo, error) {
+	object, err := s.s3Client.GetObjectWithContext(ctx, s.bucketName, s.key(path), opts)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
 	defer object.Close()
 
 	fileInfo, err := object.Stat()
 	if err != nil {
-		return nil, err
+		return nil, ObjectInfo{}, err
 	}
 	buffer := make([]byte, fileInfo.Size)
+	// a single Read may return only a part of the body
+	if _, err := io.ReadFull(object, buffer); err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return buffer, fileInfo, nil
+}
 
-	_, err = object.Read(buffer)
-	if err != nil {
-		if err != io.EOF {
-			return nil, err
+// maxWaitInterval caps the polling interval of WaitForObject.
+const maxWaitInterval = 30 * time.Second
+
+// WaitForObject polls until path exists or ctx is done, returning ctx.Err() then. The interval
+// starts at pollInterval and doubles after every poll up to 30 seconds (or pollInterval, if
+// larger). Throttling, server and network errors are polled through, other errors are returned.
+func (s *service) WaitForObject(ctx context.Context, path string, pollInterval time.Duration) error {
+	if pollInterval <= 0 {
+		return fmt.Errorf("s3 poll interval must be positive, got %s", pollInterval)
+	}
+	maxInterval := maxWaitInterval
+	if pollInterval > maxInterval {
+		maxInterval = pollInterval
+	}
+	interval := pollInterval
+	for {
+		// not through FileExists, a cached miss would hide the object
+		_, err := s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		if err == nil {
+			s.existsCache.set(s.normalizeKey(path), true)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !isNotFound(err) && !isRetryable(err) {
+			return s.wrapError(err)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
 		}
 	}
-	return buffer, nil
 }
 
+func (s *service) FileExists(path string) (bool, error) {
+	if exists, ok := s.existsCache.get(s.normalizeKey(path)); ok {
+		return exists, nil
+	}
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		_, err := s.s3Client.StatObjectWithContext(ctx, s.bucketName, s.key(path), minio.StatObjectOptions{})
+		return err
+	})
+	if isNotFound(err) {
+		s.existsCache.set(s.normalizeKey(path), false)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.existsCache.set(s.normalizeKey(path), true)
+	return true, nil
+}
+
+// FilesExist checks the existence of all paths in parallel. Paths that couldn't be checked are
+// missing in the returned map and reported in a *BatchError instead of as absent.
+func (s *service) FilesExist(paths []string) (map[string]bool, error) {
+	pathCh := make(chan ObjectInfo, len(paths))
+	for _, path := range paths {
+		pathCh <- ObjectInfo{Key: path}
+	}
+	close(pathCh)
+	mu := sync.Mutex{}
+	exists := make(map[string]bool, len(paths))
+	err := s.runObjects("check", pathCh, DefaultConcurrency, func(obj ObjectInfo) error {
+		ok, err := s.FileExists(obj.Key)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		exists[obj.Key] = ok
+		mu.Unlock()
+		return nil
+	})
+	return exists, err
+}
+
+// RemoveFile deletes the object at path, or moves it to the trash, see WithTrashPrefix.
 func (s *service) RemoveFile(path string) error {
-	return s.s3Client.RemoveObject(s.bucketName, path)
+	defer s.invalidate(path)
+	if s.opts.trashPrefix != "" && !s.inTrash(path) {
+		if err := s.moveToTrash(path); err != nil {
+			return err
+		}
+	}
+	start := time.Now()
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		return s.removeObject(ctx, path, "")
+	})
+	s.emit(Event{Op: EventDelete, Key: path, Err: err}, start)
+	return err
+}
+
+func (s *service) RemoveMatching(prefix, pattern string) ([]string, error) {
+	if _, err := pathpkg.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	keys := []string{}
+	for obj := range s.listObjects(prefix, doneCh) {
+		if obj.Err != nil {
+			return nil, s.wrapError(obj.Err)
+		}
+		if matchGlob(pattern, relativeKey(obj.Key, s.normalizeKey(prefix))) {
+			keys = append(keys, obj.Key)
+		}
+	}
+	errs := s.removeKeys(keys)
+	removed := []string{}
+	for _, key := range keys {
+		if _, failed := errs[key]; !failed {
+			removed = append(removed, key)
+		}
+	}
+	return removed, newBatchError("remove", errs)
+}
+
+// BucketUsage lists the whole bucket to sum up its objects, which takes time proportional to
+// the number of objects.
+func (s *service) BucketUsage() (*Usage, error) {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	usage := &Usage{}
+	for obj := range s.listObjects("", doneCh) {
+		if obj.Err != nil {
+			return nil, s.wrapError(obj.Err)
+		}
+		usage.Objects++
+		usage.Bytes += obj.Size
+	}
+	return usage, nil
+}
+
+func (s *service) GetBucketRegion() (string, error) {
+	s.regionMu.Lock()
+	defer s.regionMu.Unlock()
+	if s.region != "" {
+		return s.region, nil
+	}
+	region, err := s.s3Client.GetBucketLocation(s.bucketName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get region of s3 bucket (%s): %w", s.bucketName, s.wrapError(err))
+	}
+	s.region = region
+	return region, nil
+}
+
+// ListBuckets returns all buckets accessible with the service's credentials, not only the
+// bucket the service was created for.
+func (s *service) ListBuckets() ([]BucketInfo, error) {
+	var buckets []BucketInfo
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		buckets, err = s.s3Client.ListBucketsWithContext(ctx)
+		return err
+	})
+	return buckets, err
 }
@@ -0,0 +1,238 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentUse is meant to be run with -race, it shares one service with its caches between
+// goroutines.
+func TestConcurrentUse(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService(WithExistenceCache(time.Minute), WithDownloadCache(1<<20))
+	wg := sync.WaitGroup{}
+	errs := make(chan error, 100)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				path := fmt.Sprintf("concurrent/%d/%d.txt", i, j%3)
+				data := []byte(path)
+				size := int64(len(data))
+				if err := service.UploadFile(path, "text/plain", bytes.NewReader(data), &size); err != nil {
+					errs <- err
+					return
+				}
+				if downloaded, err := service.DownloadFileBytes(path); err != nil || !bytes.Equal(downloaded, data) {
+					errs <- fmt.Errorf("download of %s returned %q, %v", path, downloaded, err)
+					return
+				}
+				if exists, err := service.FileExists(path); err != nil || !exists {
+					errs <- fmt.Errorf("FileExists(%s) = %v, %v", path, exists, err)
+					return
+				}
+				if _, err := service.GetFileUrl(path, time.Minute); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := service.BucketStatus(); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := service.ListObjects("concurrent/"); err != nil {
+					errs <- err
+					return
+				}
+				if err := service.Reconfigure(fakeAccessKey, fakeSecretKey, "", false); err != nil {
+					errs <- err
+					return
+				}
+				if j%3 == 2 {
+					if err := service.RemoveFile(path); err != nil {
+						errs <- err
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestDownloadFileBytesNotFound(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	data, err := service.DownloadFileBytes("missing.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	if data != nil {
+		t.Errorf("got %d bytes for a missing object", len(data))
+	}
+}
+
+func TestEndpointScheme(t *testing.T) {
+	tests := []struct {
+		endpoint   string
+		secure     bool
+		wantHost   string
+		wantSecure bool
+		wantErr    bool
+	}{
+		{"obs.eu-de.otc.t-systems.com", true, "obs.eu-de.otc.t-systems.com", true, false},
+		{"localhost:9000", false, "localhost:9000", false, false},
+		{"https://obs.eu-de.otc.t-systems.com/", true, "obs.eu-de.otc.t-systems.com", true, false},
+		{"http://localhost:9000", true, "localhost:9000", false, false},
+		{"http://localhost:9000", false, "localhost:9000", false, false},
+		{"https://localhost:9000", false, "", false, true},
+	}
+	for _, test := range tests {
+		host, secure, err := endpointScheme(test.endpoint, test.secure)
+		if (err != nil) != test.wantErr || host != test.wantHost || secure != test.wantSecure {
+			t.Errorf("endpointScheme(%q, %v) = %q, %v, %v", test.endpoint, test.secure, host, secure, err)
+		}
+	}
+}
+
+func TestURLScheme(t *testing.T) {
+	plain := newFakeS3(t)
+	defer plain.Close()
+	secure := newFakeS3TLS(t)
+	defer secure.Close()
+	plainHost := strings.TrimPrefix(plain.URL, "http://")
+	secureHost := strings.TrimPrefix(secure.URL, "https://")
+	tests := []struct {
+		name     string
+		fake     *fakeS3
+		endpoint string
+		opts     []Option
+		want     string
+	}{
+		{"http endpoint", plain, plain.URL, nil, "http"},
+		{"WithPlainHTTP", plain, plainHost, []Option{WithPlainHTTP()}, "http"},
+		{"https endpoint", secure, secure.URL, []Option{WithRootCAs(secure.Certificate())}, "https"},
+		{"default", secure, secureHost, []Option{WithRootCAs(secure.Certificate())}, "https"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, err := NewService(test.endpoint, fakeAccessKey, fakeSecretKey, fakeBucket, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.fake.put("file.txt", []byte("data"), nil)
+			presigned, err := service.GetFileUrl("file.txt", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			public, err := service.PublicURL("file.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, u := range []*url.URL{presigned, public} {
+				if u.Scheme != test.want {
+					t.Errorf("%s has the scheme %s, want %s", u, u.Scheme, test.want)
+				}
+			}
+			// the presigned URL has to work with the scheme the endpoint speaks
+			if err := service.VerifyURL(presigned); err != nil {
+				t.Errorf("VerifyURL(%s): %v", presigned, err)
+			}
+		})
+	}
+}
+
+func TestDownloadFileBytesLarge(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService(WithDownloadCache(4 << 20))
+	data := make([]byte, 1<<20)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	fake.put("large.bin", data, nil)
+	for i := 0; i < 2; i++ {
+		// the second download is served from the cache
+		downloaded, err := service.DownloadFileBytes("large.bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(downloaded, data) {
+			t.Fatalf("download %d differs from the object", i+1)
+		}
+	}
+}
+
+func TestDownloadDirectoryListingError(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	for _, key := range []string{"dir/a", "dir/b", "dir/c"} {
+		fake.put(key, []byte(key), nil)
+	}
+	// the second page of the listing fails after dir/a failed to download
+	fake.pageSize = 2
+	fake.fail = func(r *http.Request) int {
+		if r.URL.Query().Get("continuation-token") != "" {
+			return http.StatusForbidden
+		}
+		return 0
+	}
+	localPath, err := ioutil.TempDir("", "gobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(localPath)
+	// a directory in the way of dir/a
+	if err := os.MkdirAll(filepath.Join(localPath, "a", "x"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	result, err := fake.newService().DownloadDirectoryWithResult("dir/", localPath)
+	if got := Failures(err); len(got) != 1 || got["dir/a"] == nil {
+		t.Errorf("failures are %v, want dir/a", got)
+	}
+	if resp := errorResponse(err); resp.Code != "AccessDenied" {
+		t.Errorf("error is %v, want it to wrap the listing error", err)
+	}
+	if result.Files != 1 || result.Failed != 1 {
+		t.Errorf("result is %+v, want 1 file and 1 failure", result)
+	}
+}
+
+func TestDownloadDirectoryOutsideRoot(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	fake.put("dir/../../x", []byte("x"), nil)
+	fake.put("dir/a", []byte("a"), nil)
+	parent, err := ioutil.TempDir("", "gobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	localPath := filepath.Join(parent, "a", "b")
+	result, err := fake.newService().DownloadDirectoryWithResult("dir/", localPath)
+	if got := Failures(err); len(got) != 1 || got["dir/../../x"] == nil {
+		t.Errorf("failures are %v, want dir/../../x", got)
+	}
+	if result.Files != 1 {
+		t.Errorf("downloaded %d files, want 1", result.Files)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "x")); !os.IsNotExist(err) {
+		t.Errorf("dir/../../x was written outside of %s", localPath)
+	}
+}
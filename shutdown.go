@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrShuttingDown is reported for objects a batch operation didn't start because the shutdown
+// context of the service is done.
+var ErrShuttingDown = errors.New("s3 service is shutting down")
+
+// WithShutdownContext stops batch operations such as DownloadDirectory from starting new
+// transfers once ctx is done, the first object not started fails with ErrShuttingDown.
+// Running transfers may complete within grace, after that they are cancelled.
+func WithShutdownContext(ctx context.Context, grace time.Duration) Option {
+	return func(o *options) {
+		o.shutdownCtx = ctx
+		o.shutdownGrace = grace
+	}
+}
+
+// watchShutdown returns a channel closed grace after ctx is done, or nil for a nil ctx.
+func watchShutdown(ctx context.Context, grace time.Duration) <-chan struct{} {
+	if ctx == nil {
+		return nil
+	}
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		time.Sleep(grace)
+		close(stopped)
+	}()
+	return stopped
+}
+
+// shuttingDown reports whether batch operations must not start new work.
+func (s *service) shuttingDown() bool {
+	return s.opts.shutdownCtx != nil && s.opts.shutdownCtx.Err() != nil
+}
+
+// transferContext returns a context that is also cancelled when the shutdown grace period is
+// over.
+func (s *service) transferContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if s.stopped != nil {
+		go func() {
+			select {
+			case <-s.stopped:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
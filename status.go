@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"time"
+)
+
+// bucketStatusTTL is how long BucketStatus caches the configuration of the bucket.
+const bucketStatusTTL = time.Minute
+
+// BucketStatus is the configuration state of the bucket, see (Service).BucketStatus.
+type BucketStatus struct {
+	// Versioning is "Enabled", "Suspended" or "" if versioning was never enabled.
+	Versioning string
+	// ObjectLock reports whether the bucket was created with object lock enabled.
+	ObjectLock bool
+	// LifecycleRules is the number of lifecycle rules of the bucket.
+	LifecycleRules int
+}
+
+// BucketStatus returns the versioning, object lock and lifecycle state of the bucket, e.g. to
+// check that it's set up as expected before using it. The result is cached for a minute;
+// changes made through the service, like AddLifeCycleRule, reset the cache.
+func (s *service) BucketStatus() (*BucketStatus, error) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.status != nil && time.Since(s.statusTime) < bucketStatusTTL {
+		status := *s.status
+		return &status, nil
+	}
+	status := &BucketStatus{}
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		var err error
+		status.Versioning, err = s.versioningStatus(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		config, err := s.getObjectLock(ctx)
+		switch {
+		case err == nil:
+			status.ObjectLock = config.ObjectLockEnabled == "Enabled"
+		case errorResponse(err).Code == "ObjectLockConfigurationNotFoundError":
+			status.ObjectLock = false
+		default:
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.retry(context.Background(), func(ctx context.Context) error {
+		current, err := s.getBucketLifecycle(ctx)
+		if err != nil {
+			return err
+		}
+		config := storedLifecycleConfiguration{}
+		if strings.TrimSpace(current) != "" {
+			if err := xml.Unmarshal([]byte(current), &config); err != nil {
+				return err
+			}
+		}
+		status.LifecycleRules = len(config.Rules)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.status, s.statusTime = status, time.Now()
+	result := *status
+	return &result, nil
+}
+
+// resetBucketStatus drops the cached BucketStatus after the configuration was changed.
+func (s *service) resetBucketStatus() {
+	s.statusMu.Lock()
+	s.status = nil
+	s.statusMu.Unlock()
+}
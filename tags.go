@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6/pkg/tags"
+)
+
+func (s *service) getObjectTags(ctx context.Context, path string) (map[string]string, error) {
+	var tagXML string
+	err := s.retry(ctx, func(ctx context.Context) error {
+		var err error
+		tagXML, err = s.s3Client.GetObjectTaggingWithContext(ctx, s.bucketName, s.key(path))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	objectTags, err := tags.ParseObjectXML(strings.NewReader(tagXML))
+	if err != nil {
+		return nil, err
+	}
+	return objectTags.ToMap(), nil
+}
+
+// TagPrefix adds tags to every object under prefix, keeping their other tags. Tags with the
+// same key are overwritten.
+func (s *service) TagPrefix(prefix string, newTags map[string]string) error {
+	return s.runBatch("tag", prefix, DefaultConcurrency, func(obj ObjectInfo) error {
+		ctx := context.Background()
+		merged, err := s.getObjectTags(ctx, obj.Key)
+		if err != nil {
+			return err
+		}
+		for key, value := range newTags {
+			merged[key] = value
+		}
+		start := time.Now()
+		err = s.retry(ctx, func(ctx context.Context) error {
+			return s.s3Client.PutObjectTaggingWithContext(ctx, s.bucketName, s.key(obj.Key), merged)
+		})
+		s.emit(Event{Op: EventTag, Key: obj.Key, Err: err}, start)
+		return err
+	})
+}
+
+// FindByTag returns the keys under prefix whose tag tagKey has the value tagValue. S3 can't
+// query tags, so this fetches the tags of every object under prefix, one request per object.
+func (s *service) FindByTag(prefix, tagKey, tagValue string) ([]string, error) {
+	mu := sync.Mutex{}
+	keys := []string{}
+	err := s.runBatch("read tags of", prefix, DefaultConcurrency, func(obj ObjectInfo) error {
+		objectTags, err := s.getObjectTags(context.Background(), obj.Key)
+		if err != nil {
+			return err
+		}
+		if value, ok := objectTags[tagKey]; ok && value == tagValue {
+			mu.Lock()
+			keys = append(keys, obj.Key)
+			mu.Unlock()
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
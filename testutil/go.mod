@@ -0,0 +1,9 @@
+module github.com/MaxBreida/otc-gobs/testutil
+
+go 1.13
+
+require (
+	github.com/MaxBreida/otc-gobs v0.0.0-20261014054200-2b9f1af80a02
+	github.com/minio/minio-go/v6 v6.0.57
+	github.com/testcontainers/testcontainers-go v0.14.0
+)
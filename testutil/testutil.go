@@ -0,0 +1,222 @@
+// Package testutil provides helpers for integration tests against an S3 compatible endpoint.
+// By default a MinIO container is started with testcontainers-go on first use and shared by
+// all tests of the test binary; it is removed by the testcontainers reaper when the binary
+// exits. Another endpoint can be set in the environment instead:
+//
+//	GOBS_TEST_ENDPOINT    host:port, with http:// for endpoints without TLS
+//	GOBS_TEST_ACCESS_KEY  access key
+//	GOBS_TEST_SECRET_KEY  secret key
+//
+// Tests using the helpers are skipped if GOBS_TEST_ENDPOINT isn't set and Docker isn't
+// available. The package is a module of its own, so the dependencies of testcontainers-go
+// don't reach users of the s3 package. It requires a published version of the s3 package; to
+// test changes to both, use a workspace: go work init . ./testutil
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	s3 "github.com/MaxBreida/otc-gobs"
+	"github.com/minio/minio-go/v6"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Environment variables read by NewTestService.
+const (
+	EndpointEnv  = "GOBS_TEST_ENDPOINT"
+	AccessKeyEnv = "GOBS_TEST_ACCESS_KEY"
+	SecretKeyEnv = "GOBS_TEST_SECRET_KEY"
+)
+
+// MinIOImage is the image of the container started by NewTestService.
+const MinIOImage = "minio/minio:RELEASE.2024-01-16T16-07-38Z"
+
+const (
+	containerAccessKey = "gobs-test"
+	containerSecretKey = "gobs-test-secret"
+)
+
+// container is the MinIO container shared by the tests of the binary.
+var container struct {
+	once     sync.Once
+	endpoint string
+	skip     string
+	err      error
+}
+
+// NewTestService creates a bucket with a random name and returns a service bound to it. The
+// returned function removes the bucket with all its objects and must be called when the test
+// is done, usually with defer. opts are passed to s3.NewService.
+func NewTestService(t testing.TB, opts ...s3.Option) (s3.Service, func()) {
+	t.Helper()
+	endpoint, accessKey, secretKey := endpointFromEnv(t)
+	host, secure := endpoint, true
+	switch {
+	case strings.HasPrefix(endpoint, "http://"):
+		host, secure = strings.TrimPrefix(endpoint, "http://"), false
+	case strings.HasPrefix(endpoint, "https://"):
+		host = strings.TrimPrefix(endpoint, "https://")
+	}
+	client, err := minio.New(host, accessKey, secretKey, secure)
+	if err != nil {
+		t.Fatalf("failed to create client for %s: %v", endpoint, err)
+	}
+	bucket := randomName("gobs-test-")
+	if err := client.MakeBucket(bucket, ""); err != nil {
+		t.Fatalf("failed to create test bucket %s: %v", bucket, err)
+	}
+	cleanup := func() {
+		if err := removeBucket(client, bucket); err != nil {
+			t.Errorf("failed to remove test bucket %s: %v", bucket, err)
+		}
+	}
+	service, err := s3.NewService(endpoint, accessKey, secretKey, bucket, opts...)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to create service for bucket %s: %v", bucket, err)
+	}
+	return service, cleanup
+}
+
+// SmokeTest exercises upload, download, listing, removal and lifecycle rules of service, which
+// should be bound to an empty bucket like one from NewTestService.
+func SmokeTest(t *testing.T, service s3.Service) {
+	data := []byte("hello, gobs")
+	size := int64(len(data))
+	t.Run("upload and download", func(t *testing.T) {
+		if err := service.UploadFile("smoke/hello.txt", "text/plain", bytes.NewReader(data), &size); err != nil {
+			t.Fatal(err)
+		}
+		downloaded, err := service.DownloadFileBytes("smoke/hello.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(downloaded, data) {
+			t.Fatalf("downloaded %q, uploaded %q", downloaded, data)
+		}
+	})
+	t.Run("list", func(t *testing.T) {
+		objects, err := service.ListObjects("smoke/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(objects) != 1 || objects[0].Key != "smoke/hello.txt" || objects[0].Size != size {
+			t.Fatalf("listed %+v, expected smoke/hello.txt of %d bytes", objects, size)
+		}
+	})
+	t.Run("lifecycle", func(t *testing.T) {
+		if err := service.AddLifeCycleRule("smoke-expiry", "smoke/tmp", 1); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("remove", func(t *testing.T) {
+		if err := service.RemoveFile("smoke/hello.txt"); err != nil {
+			t.Fatal(err)
+		}
+		exists, err := service.FileExists("smoke/hello.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Fatal("smoke/hello.txt still exists after RemoveFile")
+		}
+	})
+}
+
+// endpointFromEnv returns the endpoint set in the environment, or else the one of the MinIO
+// container, which is started on the first call.
+func endpointFromEnv(t testing.TB) (endpoint, accessKey, secretKey string) {
+	t.Helper()
+	if endpoint := os.Getenv(EndpointEnv); endpoint != "" {
+		return endpoint, os.Getenv(AccessKeyEnv), os.Getenv(SecretKeyEnv)
+	}
+	container.once.Do(startContainer)
+	if container.skip != "" {
+		t.Skip(container.skip)
+	}
+	if container.err != nil {
+		t.Fatalf("failed to start MinIO container: %v", container.err)
+	}
+	return container.endpoint, containerAccessKey, containerSecretKey
+}
+
+func startContainer() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	provider, err := testcontainers.NewDockerProvider()
+	if err == nil {
+		err = provider.Health(ctx)
+	}
+	if err != nil {
+		container.skip = fmt.Sprintf("neither %s is set nor Docker is available, skipping integration test: %v", EndpointEnv, err)
+		return
+	}
+	req := testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        MinIOImage,
+			Cmd:          []string{"server", "/data"},
+			ExposedPorts: []string{"9000/tcp"},
+			Env: map[string]string{
+				"MINIO_ROOT_USER":     containerAccessKey,
+				"MINIO_ROOT_PASSWORD": containerSecretKey,
+			},
+			WaitingFor: wait.ForHTTP("/minio/health/live").WithPort("9000/tcp"),
+		},
+		Started: true,
+	}
+	c, err := testcontainers.GenericContainer(ctx, req)
+	if err != nil {
+		container.err = err
+		return
+	}
+	endpoint, err := c.PortEndpoint(ctx, "9000/tcp", "http")
+	if err != nil {
+		container.err = err
+		return
+	}
+	container.endpoint = endpoint
+}
+
+func removeBucket(client *minio.Client, bucket string) error {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	keysCh := make(chan string)
+	go func() {
+		defer close(keysCh)
+		for obj := range client.ListObjectsV2(bucket, "", true, doneCh) {
+			if obj.Err == nil {
+				keysCh <- obj.Key
+			}
+		}
+	}()
+	var err error
+	for removeErr := range client.RemoveObjects(bucket, keysCh) {
+		if err == nil {
+			err = removeErr.Err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return client.RemoveBucket(bucket)
+}
+
+func randomName(prefix string) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		// fall back to the time, names only have to be unique among concurrent tests
+		return prefix + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return prefix + hex.EncodeToString(b)
+}
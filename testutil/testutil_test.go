@@ -0,0 +1,11 @@
+package testutil
+
+import (
+	"testing"
+)
+
+func TestSmoke(t *testing.T) {
+	service, cleanup := NewTestService(t)
+	defer cleanup()
+	SmokeTest(t, service)
+}
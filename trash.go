@@ -0,0 +1,70 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// WithTrashPrefix makes RemoveFile move objects into the directory prefix, e.g. "trash/",
+// instead of deleting them: the object is copied to prefix + "/" + path and then deleted. The
+// trailing slash of prefix is optional, "trash" doesn't include "trashcan/a.txt". Objects
+// already in the trash are deleted. The trash is billed like any other data until EmptyTrash
+// removes it, so it should be emptied regularly. RemoveFiles and RemoveMatching still delete
+// permanently.
+func WithTrashPrefix(prefix string) Option {
+	return func(o *options) {
+		o.trashPrefix = prefix
+	}
+}
+
+// trashDir returns the trash prefix with a trailing slash, see WithTrashPrefix.
+func (s *service) trashDir() string {
+	return strings.TrimSuffix(s.opts.trashPrefix, "/") + "/"
+}
+
+// inTrash reports whether path is an object in the trash, see WithTrashPrefix.
+func (s *service) inTrash(path string) bool {
+	return strings.HasPrefix(s.normalizeKey(path), strings.TrimSuffix(s.normalizeKey(s.opts.trashPrefix), "/")+"/")
+}
+
+// moveToTrash copies path into the trash, RemoveFile deletes the original afterwards.
+func (s *service) moveToTrash(path string) error {
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	if err != nil {
+		if isNotFound(err) {
+			// nothing to keep, deleting a missing object succeeds
+			return nil
+		}
+		return s.wrapError(err)
+	}
+	return s.copyObject(context.Background(), info, s.bucketName, s.trashDir()+path)
+}
+
+// EmptyTrash deletes the objects which were moved to the trash more than olderThan ago and
+// returns how many were deleted. Objects that couldn't be deleted are reported in a *BatchError.
+func (s *service) EmptyTrash(olderThan time.Duration) (int, error) {
+	if s.opts.trashPrefix == "" {
+		return 0, errors.New("s3 trash prefix isn't configured, see WithTrashPrefix")
+	}
+	cutoff := time.Now().Add(-olderThan)
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	keys := []string{}
+	for obj := range s.listObjects(s.trashDir(), doneCh) {
+		if obj.Err != nil {
+			return 0, s.wrapError(obj.Err)
+		}
+		// the copy into the trash is the last modification of an object in it
+		if obj.LastModified.Before(cutoff) {
+			keys = append(keys, obj.Key)
+		}
+	}
+	if err := s.RemoveFiles(keys); err != nil {
+		return len(keys) - len(Failures(err)), err
+	}
+	return len(keys), nil
+}
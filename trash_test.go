@@ -0,0 +1,30 @@
+package s3
+
+import "testing"
+
+func TestRemoveFileTrash(t *testing.T) {
+	for _, prefix := range []string{"trash", "trash/"} {
+		fake := newFakeS3(t)
+		service := fake.newService(WithTrashPrefix(prefix))
+		fake.put("trashcan/a.txt", []byte("a"), nil)
+		if err := service.RemoveFile("trashcan/a.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fake.object("trashcan/a.txt"); ok {
+			t.Errorf("%q: trashcan/a.txt wasn't removed", prefix)
+		}
+		if _, ok := fake.object("trash/trashcan/a.txt"); !ok {
+			t.Errorf("%q: trashcan/a.txt wasn't moved to trash/trashcan/a.txt", prefix)
+		}
+		if err := service.RemoveFile("trash/trashcan/a.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := fake.object("trash/trashcan/a.txt"); ok {
+			t.Errorf("%q: trash/trashcan/a.txt wasn't removed", prefix)
+		}
+		if _, ok := fake.object("trash/trash/trashcan/a.txt"); ok {
+			t.Errorf("%q: trash/trashcan/a.txt was moved to the trash again", prefix)
+		}
+		fake.Close()
+	}
+}
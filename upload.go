@@ -0,0 +1,822 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// Canned ACLs for uploaded objects.
+const (
+	ACLPrivate                = "private"
+	ACLPublicRead             = "public-read"
+	ACLPublicReadWrite        = "public-read-write"
+	ACLAuthenticatedRead      = "authenticated-read"
+	ACLBucketOwnerRead        = "bucket-owner-read"
+	ACLBucketOwnerFullControl = "bucket-owner-full-control"
+)
+
+const aclHeader = "x-amz-acl"
+
+// UploadOption configures a single upload.
+type UploadOption func(*uploadOptions)
+
+type uploadOptions struct {
+	put              minio.PutObjectOptions
+	verifySize       bool
+	removeMismatched bool
+	backup           bool
+}
+
+func newUploadOptions(contentType string, opts []UploadOption) uploadOptions {
+	o := uploadOptions{put: minio.PutObjectOptions{ContentType: contentType}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithACL sets the canned ACL of the uploaded object, overriding the service default.
+func WithACL(acl string) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.UserMetadata = withMetadata(o.put.UserMetadata, aclHeader, acl)
+	}
+}
+
+// WithVerifySize fails uploads of a known size with a *SizeMismatchError if fewer or more bytes
+// were transferred, e.g. because the reader ended early. With remove set the truncated object
+// is deleted again.
+func WithVerifySize(remove bool) UploadOption {
+	return func(o *uploadOptions) {
+		o.verifySize = true
+		o.removeMismatched = remove
+	}
+}
+
+// WithContentMD5 sends the MD5 of the data with the upload, or of every part of a multipart
+// upload, so GOBS rejects data corrupted on the way. To compute it each part is buffered in
+// memory before it is sent, which costs up to the part size per upload also for seekable readers.
+func WithContentMD5() UploadOption {
+	return func(o *uploadOptions) {
+		o.put.SendContentMd5 = true
+	}
+}
+
+// WithUploadPartSize overrides the part size of WithPartSize for one upload. It must be between
+// 5 MiB and 5 GiB.
+func WithUploadPartSize(partSize uint64) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.PartSize = partSize
+	}
+}
+
+// WithUploadThreads sets how many parts of a multipart upload are uploaded in parallel. It only
+// has an effect if the data can be read at arbitrary offsets, like files.
+func WithUploadThreads(n uint) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.NumThreads = n
+	}
+}
+
+// WithWebsiteRedirect makes the bucket's website endpoint redirect requests for the object to
+// location, which must be a path starting with "/" or an http(s) URL.
+func WithWebsiteRedirect(location string) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.WebsiteRedirectLocation = location
+	}
+}
+
+// validRedirectLocation checks a website redirect location like S3 does.
+func validRedirectLocation(location string) error {
+	if strings.HasPrefix(location, "/") {
+		return nil
+	}
+	if u, err := url.Parse(location); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return nil
+	}
+	return fmt.Errorf("s3 website redirect location must start with /, http:// or https://, got %q", location)
+}
+
+// WithBackup copies the object to a backup key before it's overwritten, as a lightweight
+// rollback for buckets without versioning. The key is path + ".bak." + the UTC time like
+// "20060102T150405Z", unless set with WithBackupKeyFormat. Backups are normal objects, they
+// are billed and have to be removed like any other. If the backup fails, nothing is uploaded.
+func WithBackup() UploadOption {
+	return func(o *uploadOptions) {
+		o.backup = true
+	}
+}
+
+// WithBackupKeyFormat sets the key of the backups made by WithBackup, format is called with the
+// path of the object and the time of the backup.
+func WithBackupKeyFormat(format func(path string, t time.Time) string) Option {
+	return func(o *options) {
+		o.backupKeyFormat = format
+	}
+}
+
+func defaultBackupKey(path string, t time.Time) string {
+	return path + ".bak." + t.UTC().Format(amzDateFormat)
+}
+
+// backupObject copies path to its backup key if it exists, see WithBackup.
+func (s *service) backupObject(path string) error {
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return s.wrapError(err)
+	}
+	format := s.opts.backupKeyFormat
+	if format == nil {
+		format = defaultBackupKey
+	}
+	return s.copyObject(context.Background(), info, s.bucketName, format(path, time.Now()))
+}
+
+// remainingBytes returns the number of bytes left in data if it is an io.Seeker.
+func remainingBytes(data io.Reader) (int64, bool) {
+	seeker, ok := data.(io.Seeker)
+	if !ok {
+		return 0, false
+	}
+	current, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := seeker.Seek(current, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return end - current, true
+}
+
+// SizeMismatchError is returned when an upload transferred a different number of bytes than
+// expected, or when a seekable reader holds a different number of bytes.
+type SizeMismatchError struct {
+	Key      string
+	Expected int64
+	Actual   int64
+}
+
+func (e *SizeMismatchError) Error() string {
+	return fmt.Sprintf("uploaded %d bytes to %s, expected %d", e.Actual, e.Key, e.Expected)
+}
+
+// WithDefaultACL sets the canned ACL of all objects uploaded by the service, e.g. public-read
+// for buckets serving public files. By default no ACL is sent and objects are private.
+func WithDefaultACL(acl string) Option {
+	return func(o *options) {
+		o.defaultACL = acl
+	}
+}
+
+// Storage classes of GOBS. WARM and COLD are sent as their S3 names STANDARD_IA and GLACIER,
+// which are accepted as well.
+const (
+	StorageClassStandard = "STANDARD"
+	StorageClassWarm     = "WARM"
+	StorageClassCold     = "COLD"
+)
+
+var s3StorageClasses = map[string]string{
+	StorageClassStandard: "STANDARD",
+	StorageClassWarm:     "STANDARD_IA",
+	StorageClassCold:     "GLACIER",
+	"STANDARD_IA":        "STANDARD_IA",
+	"GLACIER":            "GLACIER",
+}
+
+// s3StorageClass returns the S3 name of a GOBS storage class.
+func s3StorageClass(class string) (string, error) {
+	if s3Class, ok := s3StorageClasses[strings.ToUpper(class)]; ok {
+		return s3Class, nil
+	}
+	return "", fmt.Errorf("s3 storage class must be STANDARD, WARM or COLD, got %q", class)
+}
+
+// WithDefaultStorageClass stores new objects in class, one of StorageClassStandard,
+// StorageClassWarm and StorageClassCold, unless an upload sets its own with WithStorageClass.
+// It applies to uploads, not to server-side copies.
+func WithDefaultStorageClass(class string) Option {
+	return func(o *options) {
+		o.defaultStorageClass = class
+	}
+}
+
+// WithStorageClass stores the uploaded object in class, overriding the service default.
+func WithStorageClass(class string) UploadOption {
+	return func(o *uploadOptions) {
+		o.put.StorageClass = class
+	}
+}
+
+// WithKeepFailedUploads keeps the parts of failed multipart uploads that minio-go couldn't abort
+// itself, e.g. because the context of the upload was done, to inspect them. By default the
+// multipart upload created by the failed upload is aborted; other uploads to the same key, like
+// those of InitiateUpload, are left alone. Kept parts are billed until they are aborted, see
+// AddAbortIncompleteUploadRule.
+func WithKeepFailedUploads() Option {
+	return func(o *options) {
+		o.keepFailedUploads = true
+	}
+}
+
+// WithDefaultContentType sets the content type of uploads for which the caller passes an empty
+// content type. Without it such objects are stored as application/octet-stream.
+func WithDefaultContentType(contentType string) Option {
+	return func(o *options) {
+		o.defaultContentType = contentType
+	}
+}
+
+// WithUploadRetryBuffer buffers uploads from readers that can't seek in memory if they are at
+// most limit bytes, so they can be retried. Uploads from readers that can seek are rewound
+// before a retry; other uploads are never retried, as the reader was already consumed.
+func WithUploadRetryBuffer(limit int64) Option {
+	return func(o *options) {
+		o.uploadRetryBuffer = limit
+	}
+}
+
+// WithSpillToDisk writes uploads of unknown size that are larger than threshold bytes to a
+// temporary file in dir, or the default directory for temporary files if dir is empty, before
+// uploading them. Knowing the size, objects up to the multipart threshold are sent with a single
+// PUT, larger ones in parts without buffering them in memory, and retries read the file again.
+// Smaller uploads are buffered in memory. The file is removed after the upload.
+func WithSpillToDisk(threshold int64, dir string) Option {
+	return func(o *options) {
+		o.spillToDisk = true
+		o.spillThreshold = threshold
+		o.spillDir = dir
+	}
+}
+
+// spill reads data of unknown size into memory up to threshold bytes and into a temporary file
+// beyond. It returns a reader of the same data and its size, and a function removing the file.
+func spill(data io.Reader, threshold int64, dir string) (io.ReadSeeker, int64, func(), error) {
+	buf := &bytes.Buffer{}
+	n, err := io.CopyN(buf, data, threshold+1)
+	switch {
+	case err == io.EOF:
+		return bytes.NewReader(buf.Bytes()), n, func() {}, nil
+	case err != nil:
+		return nil, 0, nil, err
+	}
+	file, err := ioutil.TempFile(dir, "gobs-upload-")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+	size, err := io.Copy(file, io.MultiReader(buf, data))
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	return file, size, cleanup, nil
+}
+
+// WithDefaultMetadata adds metadata to every uploaded object, e.g. the uploading service or
+// the environment. Metadata passed for a single upload takes precedence. Keys must be user
+// metadata, standard headers such as Content-Type and x-amz- headers are rejected by NewService.
+func WithDefaultMetadata(metadata map[string]string) Option {
+	return func(o *options) {
+		o.defaultMetadata = make(map[string]string, len(metadata))
+		for key, value := range metadata {
+			o.defaultMetadata[key] = value
+		}
+	}
+}
+
+// checkUserMetadataKey returns an error if key isn't a user metadata key.
+func checkUserMetadataKey(key string) error {
+	lower := strings.ToLower(key)
+	if strings.HasPrefix(lower, "x-amz-meta-") {
+		return nil
+	}
+	for _, header := range preservedHeaders {
+		if lower == strings.ToLower(header) {
+			return fmt.Errorf("s3 metadata key %s is a reserved header", key)
+		}
+	}
+	if strings.HasPrefix(lower, "x-amz-") || lower == "content-md5" || lower == "content-length" {
+		return fmt.Errorf("s3 metadata key %s is a reserved header", key)
+	}
+	return nil
+}
+
+// ErrContentTypeNotAllowed is returned for uploads whose content type isn't allowed by
+// WithAllowedContentTypes.
+var ErrContentTypeNotAllowed = errors.New("s3 content type not allowed")
+
+// WithAllowedContentTypes rejects uploads with a content type not in allowed before anything is
+// sent. Entries are media types like "application/pdf" or wildcards like "image/*".
+func WithAllowedContentTypes(allowed []string) Option {
+	return func(o *options) {
+		o.allowedContentTypes = append([]string(nil), allowed...)
+	}
+}
+
+func contentTypeAllowed(allowed []string, contentType string) bool {
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == media || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(media, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// withMetadata returns a copy of metadata with key set to value.
+func withMetadata(metadata map[string]string, key, value string) map[string]string {
+	merged := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	merged[key] = value
+	return merged
+}
+
+func hasMetadata(metadata map[string]string, key string) bool {
+	for k := range metadata {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareUpload validates opts of an upload to path and applies the defaults of the service.
+func (s *service) prepareUpload(path string, opts *minio.PutObjectOptions) error {
+	if opts.PartSize != 0 {
+		if err := validPartSize(opts.PartSize); err != nil {
+			return err
+		}
+	}
+	if opts.StorageClass == "" {
+		opts.StorageClass = s.opts.defaultStorageClass
+	}
+	if opts.StorageClass != "" {
+		class, err := s3StorageClass(opts.StorageClass)
+		if err != nil {
+			return err
+		}
+		opts.StorageClass = class
+	}
+	if opts.WebsiteRedirectLocation != "" {
+		if err := validRedirectLocation(opts.WebsiteRedirectLocation); err != nil {
+			return err
+		}
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = s.opts.defaultContentType
+	}
+	if s.opts.allowedContentTypes != nil && !contentTypeAllowed(s.opts.allowedContentTypes, opts.ContentType) {
+		return fmt.Errorf("%w: %q for %s", ErrContentTypeNotAllowed, opts.ContentType, path)
+	}
+	for key, value := range s.opts.defaultMetadata {
+		if !hasMetadata(opts.UserMetadata, key) {
+			opts.UserMetadata = withMetadata(opts.UserMetadata, key, value)
+		}
+	}
+	if s.opts.defaultACL != "" && !hasMetadata(opts.UserMetadata, aclHeader) {
+		opts.UserMetadata = withMetadata(opts.UserMetadata, aclHeader, s.opts.defaultACL)
+	}
+	return nil
+}
+
+func (s *service) putObject(ctx context.Context, path string, data io.Reader, size int64, opts minio.PutObjectOptions) (int64, error) {
+	if err := s.prepareUpload(path, &opts); err != nil {
+		return 0, err
+	}
+	defer s.invalidate(path)
+	ctx, cancel := s.transferContext(ctx)
+	defer cancel()
+	if size < 0 && s.opts.spillToDisk {
+		spilled, n, cleanup, err := spill(data, s.opts.spillThreshold, s.opts.spillDir)
+		if err != nil {
+			return 0, err
+		}
+		defer cleanup()
+		data, size = spilled, n
+	}
+	threshold := s.opts.multipartThreshold
+	if size < 0 && threshold > 0 {
+		// buffer up to the threshold to find out whether a single PUT suffices
+		buf := &bytes.Buffer{}
+		n, err := io.CopyN(buf, data, threshold+1)
+		switch {
+		case err == io.EOF:
+			size = n
+			data = bytes.NewReader(buf.Bytes())
+		case err != nil:
+			return 0, err
+		default:
+			data = io.MultiReader(buf, data)
+		}
+	}
+	if (size < 0 || size > threshold) && opts.PartSize == 0 {
+		opts.PartSize = s.opts.partSize
+	}
+	seeker, replayable := data.(io.Seeker)
+	var start int64
+	if replayable {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			replayable = false
+		}
+	}
+	if !replayable && s.opts.uploadRetryBuffer > 0 {
+		// buffer small streams so a retry sends the same bytes again
+		buf := &bytes.Buffer{}
+		n, err := io.CopyN(buf, data, s.opts.uploadRetryBuffer+1)
+		switch {
+		case err == io.EOF:
+			reader := bytes.NewReader(buf.Bytes())
+			size, data, seeker, replayable = n, reader, reader, true
+		case err != nil:
+			return 0, err
+		default:
+			data = io.MultiReader(buf, data)
+		}
+	}
+	// the multipart uploads minio creates, to abort them if it can't
+	uploads := &createdUploads{}
+	ctx = context.WithValue(ctx, createdUploadsKey{}, uploads)
+	var n int64
+	upload := func(ctx context.Context) error {
+		var err error
+		n, err = s.s3Client.PutObjectWithContext(ctx, s.bucketName, s.key(path), data, size, opts)
+		return err
+	}
+	begin := time.Now()
+	var err error
+	if replayable {
+		err = s.retry(ctx, func(ctx context.Context) error {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+			return upload(ctx)
+		})
+	} else {
+		// a partially consumed reader can't be uploaded again
+		err = s.wrapError(upload(ctx))
+	}
+	if err != nil && !s.opts.keepFailedUploads {
+		s.abortUploads(path, uploads.list())
+	}
+	s.emit(Event{Op: EventUpload, Key: path, Size: n, Err: err}, begin)
+	return n, err
+}
+
+// abortUploads aborts the multipart uploads of path with the given IDs, which a failed upload
+// left behind. minio aborts them itself, but with the context of the upload, which fails once
+// it's done, so uploads it did abort are skipped.
+func (s *service) abortUploads(path string, uploadIDs []string) {
+	core := minio.Core{Client: s.s3Client}
+	for _, uploadID := range uploadIDs {
+		err := core.AbortMultipartUpload(s.bucketName, s.key(path), uploadID)
+		switch {
+		case err == nil:
+			s.logger.Printf("s3: aborted incomplete upload %s of %s", uploadID, path)
+		case errorResponse(err).Code != "NoSuchUpload":
+			s.logger.Printf("s3: failed to abort incomplete upload %s of %s: %v", uploadID, path, err)
+		}
+	}
+}
+
+type createdUploadsKey struct{}
+
+// createdUploads collects the IDs of the multipart uploads initiated with a context, which the
+// transport reads from the responses.
+type createdUploads struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (u *createdUploads) list() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.ids...)
+}
+
+// record adds the upload ID of resp if req initiated a multipart upload.
+func (u *createdUploads) record(req *http.Request, resp *http.Response) error {
+	if _, ok := req.URL.Query()["uploads"]; !ok || req.Method != http.MethodPost || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	result := struct {
+		UploadID string `xml:"UploadId"`
+	}{}
+	if err := xml.Unmarshal(data, &result); err != nil {
+		// left to minio to report
+		return nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.ids = append(u.ids, result.UploadID)
+	return nil
+}
+
+// UploadFileWithOptions uploads with the given minio options as they are, for settings not
+// covered by UploadOption. Only unset fields are filled in from the service configuration: the
+// x-amz-acl metadata from WithDefaultACL and PartSize from WithPartSize.
+func (s *service) UploadFileWithOptions(path string, data io.Reader, objectSize *int64, opts minio.PutObjectOptions) error {
+	size := int64(-1)
+	if objectSize != nil {
+		size = *objectSize
+	}
+	_, err := s.putObject(context.Background(), path, data, size, opts)
+	return err
+}
+
+// UploadResult describes a finished upload. Parts is the number of parts of a multipart upload
+// and 0 for a single PUT; the ETag of multipart uploads is not the MD5 of the object.
+type UploadResult struct {
+	Size  int64
+	ETag  string
+	Parts int
+}
+
+// Multipart reports whether the object was uploaded as multipart upload.
+func (r *UploadResult) Multipart() bool {
+	return r.Parts > 0
+}
+
+// etagParts returns the part count of a multipart ETag ("<md5 of part md5s>-<parts>"), or 0.
+func etagParts(etag string) int {
+	i := strings.LastIndex(etag, "-")
+	if i < 0 {
+		return 0
+	}
+	parts, err := strconv.Atoi(etag[i+1:])
+	if err != nil {
+		return 0
+	}
+	return parts
+}
+
+// UploadFileWithResult is UploadFile but also reports the ETag and whether the object was
+// uploaded in parts, which costs an additional HEAD request.
+func (s *service) UploadFileWithResult(path, contentType string, data io.Reader, objectSize *int64, opts ...UploadOption) (*UploadResult, error) {
+	if err := s.UploadFile(path, contentType, data, objectSize, opts...); err != nil {
+		return nil, err
+	}
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s.wrapError(err)
+	}
+	return &UploadResult{Size: info.Size, ETag: info.ETag, Parts: etagParts(info.ETag)}, nil
+}
+
+// ErrChecksumRejected is returned when GOBS rejects the data of UploadFileWithSHA256.
+var ErrChecksumRejected = errors.New("s3 upload rejected, the data doesn't match its SHA256")
+
+// UploadFileWithSHA256 uploads size bytes of data whose SHA256 (hex encoded) was computed up
+// front. GOBS verifies the data against it and rejects the upload with ErrChecksumRejected on
+// a mismatch, the object isn't changed then. The data is sent as it's read, in a single request,
+// so size must be known and at most 5 GiB.
+func (s *service) UploadFileWithSHA256(path, contentType string, data io.Reader, size int64, sum string, opts ...UploadOption) error {
+	if size < 0 || size > maxPartSize {
+		return fmt.Errorf("s3 upload with SHA256 must have a known size of at most %d bytes, got %d", int64(maxPartSize), size)
+	}
+	if decoded, err := hex.DecodeString(sum); err != nil || len(decoded) != sha256.Size {
+		return fmt.Errorf("s3 SHA256 must be %d hex encoded bytes, got %q", sha256.Size, sum)
+	}
+	o := newUploadOptions(contentType, opts)
+	if err := s.prepareUpload(path, &o.put); err != nil {
+		return err
+	}
+	if o.backup {
+		if err := s.backupObject(path); err != nil {
+			return err
+		}
+	}
+	defer s.invalidate(path)
+	header := o.put.Header()
+	upload := func(ctx context.Context) error {
+		resp, err := s.doRawStream(ctx, http.MethodPut, path, nil, header, data, size, strings.ToLower(sum))
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}
+	begin := time.Now()
+	var err error
+	if seeker, ok := data.(io.Seeker); ok {
+		start, seekErr := seeker.Seek(0, io.SeekCurrent)
+		if seekErr != nil {
+			return seekErr
+		}
+		err = s.retry(context.Background(), func(ctx context.Context) error {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+			return upload(ctx)
+		})
+	} else {
+		// a partially consumed reader can't be uploaded again
+		ctx, cancel := s.transferContext(context.Background())
+		err = s.wrapError(upload(ctx))
+		cancel()
+	}
+	if code := errorResponse(err).Code; code == "XAmzContentSHA256Mismatch" || code == "BadDigest" {
+		err = fmt.Errorf("%w: %s: %v", ErrChecksumRejected, path, err)
+	}
+	var uploaded int64
+	if err == nil {
+		uploaded = size
+	}
+	s.emit(Event{Op: EventUpload, Key: path, Size: uploaded, Err: err}, begin)
+	return err
+}
+
+// AppendToObject appends data to an appendable object using the OBS append upload, creating
+// the object if it doesn't exist. Objects created by regular uploads can't be appended to. The
+// upload defaults of the service, e.g. WithDefaultMetadata, apply to the created object.
+// Seekable readers are streamed, others are first written to a temporary file, see
+// WithSpillToDisk. The append position is the size of the object before the append; GOBS
+// rejects an append at another position, so of concurrent appends to the same object only
+// one succeeds and the others fail without writing. It isn't safe for concurrent writers
+// unless they retry.
+func (s *service) AppendToObject(path string, data io.Reader) error {
+	opts := minio.PutObjectOptions{}
+	if err := s.prepareUpload(path, &opts); err != nil {
+		return err
+	}
+	size, ok := remainingBytes(data)
+	if !ok {
+		spilled, n, cleanup, err := spill(data, s.opts.spillThreshold, s.opts.spillDir)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		data, size = spilled, n
+	}
+	position := int64(0)
+	info, err := s.s3Client.StatObject(s.bucketName, s.key(path), minio.StatObjectOptions{})
+	switch {
+	case err == nil:
+		position = info.Size
+	case !isNotFound(err):
+		return s.wrapError(err)
+	}
+	query := url.Values{}
+	query.Set("append", "")
+	query.Set("position", strconv.FormatInt(position, 10))
+	defer s.invalidate(path)
+	start := time.Now()
+	resp, err := s.doRawStream(context.Background(), http.MethodPost, path, query, opts.Header(), data, size, "UNSIGNED-PAYLOAD")
+	if err == nil {
+		err = resp.Body.Close()
+	}
+	err = s.wrapError(err)
+	s.emit(Event{Op: EventAppend, Key: path, Size: size, Err: err}, start)
+	return err
+}
+
+// UploadLocalFile uploads the file at localPath, deriving the content type from the file
+// extension if contentType is empty. Large files are uploaded in parts in parallel, see
+// WithUploadThreads and WithUploadPartSize.
+func (s *service) UploadLocalFile(localPath, path, contentType string, opts ...UploadOption) error {
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(localPath))
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	return s.UploadFile(path, contentType, file, &size, opts...)
+}
+
+// UploadItem is a single upload of UploadBatch. Size may be nil if it isn't known.
+type UploadItem struct {
+	Data        io.Reader
+	ContentType string
+	Size        *int64
+}
+
+// UploadBatch uploads items to their paths, DefaultConcurrency at a time. Failed paths are
+// reported in a *BatchError; once ctx is done no further uploads are started and running ones
+// are aborted.
+func (s *service) UploadBatch(ctx context.Context, items map[string]UploadItem) error {
+	objectCh := make(chan ObjectInfo, len(items))
+	for path := range items {
+		objectCh <- ObjectInfo{Key: path}
+	}
+	close(objectCh)
+	return s.runObjects("upload", objectCh, DefaultConcurrency, func(obj ObjectInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item := items[obj.Key]
+		size := int64(-1)
+		if item.Size != nil {
+			size = *item.Size
+		}
+		_, err := s.putObject(ctx, obj.Key, item.Data, size, minio.PutObjectOptions{ContentType: item.ContentType})
+		return err
+	})
+}
+
+// UploadDirectory uploads all files below localPath to the same relative keys under path.
+// The content type is derived from the file extension.
+func (s *service) UploadDirectory(localPath, path string, opts ...DirectoryOption) error {
+	o := newDirectoryOptions(opts)
+	if o.glob != "" {
+		if _, err := pathpkg.Match(o.glob, ""); err != nil {
+			return err
+		}
+	}
+	files := map[string]string{}
+	objects := []ObjectInfo{}
+	err := filepath.Walk(localPath, func(file string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(localPath, file)
+		if err != nil {
+			return err
+		}
+		obj := ObjectInfo{Key: filepath.ToSlash(rel), Size: info.Size(), LastModified: info.ModTime()}
+		if !o.matches(obj, "") {
+			return nil
+		}
+		name := o.mapKey(obj.Key)
+		if name == "" {
+			return nil
+		}
+		obj.Key = name
+		if path != "" {
+			obj.Key = strings.TrimSuffix(path, "/") + "/" + name
+		}
+		files[obj.Key] = file
+		objects = append(objects, obj)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	objectCh := make(chan ObjectInfo, len(objects))
+	for _, obj := range objects {
+		objectCh <- obj
+	}
+	close(objectCh)
+	return s.runObjectsContext(context.Background(), "upload", objectCh, o.concurrency, o.failFast, func(ctx context.Context, obj ObjectInfo) error {
+		file, err := os.Open(files[obj.Key])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		contentType := mime.TypeByExtension(filepath.Ext(obj.Key))
+		_, err = s.putObject(ctx, obj.Key, file, obj.Size, minio.PutObjectOptions{ContentType: contentType})
+		return err
+	})
+}
@@ -0,0 +1,134 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUploadFileShortReader(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	data := []byte("only half of it")
+	size := int64(2 * len(data))
+	err := service.UploadFile("short.txt", "text/plain", bytes.NewReader(data), &size, WithVerifySize(true))
+	mismatch := &SizeMismatchError{}
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("got %v, want a *SizeMismatchError", err)
+	}
+	if mismatch.Expected != size || mismatch.Actual != int64(len(data)) {
+		t.Errorf("got %v, want %d of %d bytes", err, len(data), size)
+	}
+	if _, ok := fake.object("short.txt"); ok {
+		t.Error("truncated object was stored")
+	}
+}
+
+func TestUploadFileShortStream(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService()
+	data := []byte("only half of it")
+	size := int64(2 * len(data))
+	// hide the io.Seeker of the bytes.Reader
+	stream := struct{ io.Reader }{bytes.NewReader(data)}
+	if err := service.UploadFile("short.txt", "text/plain", stream, &size, WithVerifySize(true)); err == nil {
+		t.Fatal("upload of a short stream succeeded")
+	}
+	if obj, ok := fake.object("short.txt"); ok {
+		t.Errorf("truncated object of %d bytes was stored", len(obj.data))
+	}
+}
+
+func TestUploadFileAbortsFailedMultipartUpload(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		fake := newFakeS3(t)
+		// fail the second part of the upload, the single attempt of WithRetry stops minio-go
+		// before it can abort the upload itself
+		fake.fail = func(r *http.Request) int {
+			if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2" {
+				return http.StatusInternalServerError
+			}
+			return 0
+		}
+		opts := []Option{WithMultipartThreshold(minPartSize), WithPartSize(minPartSize), WithRetry(1)}
+		if keep {
+			opts = append(opts, WithKeepFailedUploads())
+		}
+		service := fake.newService(opts...)
+		data := make([]byte, 3*minPartSize)
+		size := int64(len(data))
+		if err := service.UploadFile("large.bin", "", bytes.NewReader(data), &size); err == nil {
+			t.Fatal("upload with a failing part succeeded")
+		}
+		if _, ok := fake.object("large.bin"); ok {
+			t.Error("failed upload created the object")
+		}
+		want := 0
+		if keep {
+			want = 1
+		}
+		if n := fake.incompleteUploads(); n != want {
+			t.Errorf("with WithKeepFailedUploads %v %d incomplete uploads are left, want %d", keep, n, want)
+		}
+		fake.Close()
+	}
+}
+
+func TestUploadFileKeepsOtherUploads(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService(WithMultipartThreshold(minPartSize), WithPartSize(minPartSize), WithRetry(1))
+	if _, err := service.InitiateUpload("large.bin", ""); err != nil {
+		t.Fatal(err)
+	}
+	fake.fail = func(r *http.Request) int {
+		if r.Method == http.MethodPut {
+			return http.StatusInternalServerError
+		}
+		return 0
+	}
+	// a single PUT leaves no upload behind
+	small := []byte("small")
+	size := int64(len(small))
+	before := len(fake.requestLog())
+	if err := service.UploadFile("large.bin", "", bytes.NewReader(small), &size); err == nil {
+		t.Fatal("failing upload succeeded")
+	}
+	if requests := fake.requestLog()[before:]; len(requests) != 1 {
+		t.Errorf("failed single PUT sent %v", requests)
+	}
+	data := make([]byte, 3*minPartSize)
+	size = int64(len(data))
+	if err := service.UploadFile("large.bin", "", bytes.NewReader(data), &size); err == nil {
+		t.Fatal("failing multipart upload succeeded")
+	}
+	if n := fake.incompleteUploads(); n != 1 {
+		t.Errorf("%d incomplete uploads are left, want the one of InitiateUpload", n)
+	}
+}
+
+func TestAppendToObject(t *testing.T) {
+	fake := newFakeS3(t)
+	defer fake.Close()
+	service := fake.newService(WithDefaultMetadata(map[string]string{"origin": "test"}))
+	// a stream of unknown size and a seekable reader
+	if err := service.AppendToObject("log.txt", ioutil.NopCloser(strings.NewReader("a"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.AppendToObject("log.txt", strings.NewReader("bc")); err != nil {
+		t.Fatal(err)
+	}
+	obj, ok := fake.object("log.txt")
+	if !ok || string(obj.data) != "abc" {
+		t.Fatalf("object is %v, want abc", obj)
+	}
+	if got := obj.header.Get("X-Amz-Meta-Origin"); got != "test" {
+		t.Errorf("default metadata is %q, want test", got)
+	}
+}
@@ -0,0 +1,185 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// ErrNotDeleteMarker is returned by Undelete if the object isn't soft deleted.
+var ErrNotDeleteMarker = errors.New("latest version of the object is not a delete marker")
+
+// ErrVersionNotFound is returned when the requested version of an object doesn't exist.
+var ErrVersionNotFound = errors.New("s3 object version not found")
+
+type objectVersion struct {
+	Key          string
+	VersionID    string `xml:"VersionId"`
+	IsLatest     bool
+	DeleteMarker bool `xml:"-"`
+}
+
+type listVersionsResult struct {
+	Versions      []objectVersion `xml:"Version"`
+	DeleteMarkers []objectVersion `xml:"DeleteMarker"`
+}
+
+type versioningConfiguration struct {
+	Status string
+}
+
+// latestVersion returns the current version of path, which may be a delete marker.
+func (s *service) latestVersion(ctx context.Context, path string) (*objectVersion, error) {
+	query := url.Values{}
+	query.Set("versions", "")
+	query.Set("prefix", s.key(path))
+	resp, err := s.doRaw(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := listVersionsResult{}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	for _, marker := range result.DeleteMarkers {
+		if marker.Key == s.key(path) && marker.IsLatest {
+			marker.DeleteMarker = true
+			return &marker, nil
+		}
+	}
+	for _, version := range result.Versions {
+		if version.Key == s.key(path) && version.IsLatest {
+			return &version, nil
+		}
+	}
+	return nil, minio.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		BucketName: s.bucketName,
+		Key:        path,
+	}
+}
+
+func (s *service) versioningEnabled(ctx context.Context) (bool, error) {
+	status, err := s.versioningStatus(ctx)
+	return status == "Enabled", err
+}
+
+// versioningStatus returns "Enabled", "Suspended" or "" if versioning was never enabled.
+func (s *service) versioningStatus(ctx context.Context) (string, error) {
+	query := url.Values{}
+	query.Set("versioning", "")
+	resp, err := s.doRaw(ctx, http.MethodGet, "", query, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	config := versioningConfiguration{}
+	if err := xml.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return "", err
+	}
+	return config.Status, nil
+}
+
+// SoftDelete hides an object behind a delete marker, keeping its versions so it can be restored
+// with Undelete. It fails if versioning isn't enabled on the bucket, as the object would be
+// gone for good.
+func (s *service) SoftDelete(path string) error {
+	enabled, err := s.versioningEnabled(context.Background())
+	if err != nil {
+		return s.wrapError(err)
+	}
+	if !enabled {
+		return fmt.Errorf("s3 bucket (%s) must have versioning enabled to soft delete", s.bucketName)
+	}
+	return s.RemoveFile(path)
+}
+
+// Undelete restores the previous version of a soft deleted object by removing its delete marker.
+func (s *service) Undelete(path string) error {
+	defer s.invalidate(path)
+	start := time.Now()
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		latest, err := s.latestVersion(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !latest.DeleteMarker {
+			return ErrNotDeleteMarker
+		}
+		return s.removeObject(ctx, path, latest.VersionID)
+	})
+	s.emit(Event{Op: EventUndelete, Key: path, Err: err}, start)
+	return err
+}
+
+// DownloadVersionBytes reads the given version of an object into memory, e.g. to compare it with
+// the current version. It fails with ErrVersionNotFound if the version doesn't exist.
+func (s *service) DownloadVersionBytes(path, versionID string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("versionId", versionID)
+	var data []byte
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodGet, path, query, nil, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = ioutil.ReadAll(resp.Body)
+		return err
+	})
+	if err != nil {
+		return nil, versionError(err, path, versionID)
+	}
+	return data, nil
+}
+
+// DownloadVersionRange reads length bytes starting at offset of the given version of an object,
+// e.g. to inspect the header of a historical version. Less bytes are returned if the range
+// reaches past the end of the object and none if it starts there. It fails with
+// ErrVersionNotFound if the version doesn't exist.
+func (s *service) DownloadVersionRange(path, versionID string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 1 {
+		return nil, fmt.Errorf("invalid range of %d bytes at offset %d", length, offset)
+	}
+	query := url.Values{}
+	query.Set("versionId", versionID)
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	var data []byte
+	err := s.retry(context.Background(), func(ctx context.Context) error {
+		resp, err := s.doRaw(ctx, http.MethodGet, path, query, header, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		data, err = ioutil.ReadAll(io.LimitReader(resp.Body, length))
+		return err
+	})
+	if errorResponse(err).StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		return []byte{}, nil
+	}
+	if err != nil {
+		return nil, versionError(err, path, versionID)
+	}
+	return data, nil
+}
+
+// versionError maps the error of a missing version to ErrVersionNotFound.
+func versionError(err error, path, versionID string) error {
+	if resp := errorResponse(err); resp.Code == "NoSuchVersion" || resp.Code == "NoSuchKey" ||
+		resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: version %s of %s: %v", ErrVersionNotFound, versionID, path, err)
+	}
+	return err
+}